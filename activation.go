@@ -0,0 +1,86 @@
+package easycel
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/google/cel-go/interpreter"
+)
+
+var _ interpreter.Activation = (*structActivation)(nil)
+
+// structActivation resolves variables from the fields of an input struct.
+type structActivation struct {
+	fields   map[string]int
+	refValue reflect.Value
+}
+
+// ResolveName implements the interpreter.Activation interface method.
+func (a *structActivation) ResolveName(name string) (any, bool) {
+	fieldIndex, ok := a.fields[name]
+	if !ok {
+		return nil, false
+	}
+	return a.refValue.Field(fieldIndex).Interface(), true
+}
+
+// Parent implements the interpreter.Activation interface method.
+func (a *structActivation) Parent() interpreter.Activation {
+	return nil
+}
+
+// RegisterInput registers the exported fields of the input struct as variables,
+// named after the same tag rules as the fields of registered types and documented by their doc tag.
+// Values of the input struct are evaluated through NewActivation.
+func (r *Registry) RegisterInput(input any) error {
+	typ := reflect.TypeOf(input)
+	if typ != nil && typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return fmt.Errorf("input must be a struct, got %T", input)
+	}
+	if r.inputType != nil {
+		return fmt.Errorf("input %v already registered", r.inputType)
+	}
+
+	fields := getStructFieldMap(typ, r.tagName)
+	for _, name := range getStructFields(typ, r.tagName) {
+		field := typ.Field(fields[name])
+		if !isSupportedType(field.Type) {
+			continue
+		}
+		err := r.DeclareVariable(name, field.Type)
+		if err != nil {
+			return err
+		}
+		if doc := field.Tag.Get(docTagName); doc != "" {
+			r.variableDocs[name] = doc
+		}
+	}
+	r.inputType = typ
+	r.inputFields = fields
+	return nil
+}
+
+// NewActivation creates an activation resolving variables from the fields of the input struct,
+// which must be of the type registered with RegisterInput.
+func (r *Registry) NewActivation(input any) (interpreter.Activation, error) {
+	if r.inputType == nil {
+		return nil, fmt.Errorf("no input registered")
+	}
+	refValue := reflect.ValueOf(input)
+	if refValue.Kind() == reflect.Pointer {
+		if refValue.IsNil() {
+			return nil, fmt.Errorf("input must not be nil")
+		}
+		refValue = refValue.Elem()
+	}
+	if !refValue.IsValid() || refValue.Type() != r.inputType {
+		return nil, fmt.Errorf("input must be %v, got %T", r.inputType, input)
+	}
+	return &structActivation{
+		fields:   r.inputFields,
+		refValue: refValue,
+	}, nil
+}
@@ -0,0 +1,241 @@
+package easycel
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/operators"
+	"github.com/google/cel-go/common/types"
+)
+
+// Analysis lists the variables, struct fields and registered functions referenced by an expression.
+type Analysis struct {
+	Variables []*VariableReference
+	Fields    []*FieldReference
+	Functions []*FunctionReference
+}
+
+// VariableReference is a variable referenced by an expression.
+type VariableReference struct {
+	Name string
+	Type *cel.Type
+	// GoType is nil if the variable was not declared from a Go type.
+	GoType reflect.Type
+}
+
+// FieldReference is a struct field referenced by an expression.
+type FieldReference struct {
+	// Path is the source of the selection, e.g. "msg.meta.name" or "msg.?next.?name".
+	Path string
+	// Struct is the CEL type name of the struct and Field the selected field name.
+	Struct string
+	Field  string
+	Type   *cel.Type
+	// GoType is nil if the struct is not a registered native struct.
+	GoType reflect.Type
+}
+
+// FunctionReference is a registered function called by an expression.
+type FunctionReference struct {
+	Name string
+	// Overloads are the overloads the call may resolve to,
+	// more than one if the argument types are only known at runtime.
+	Overloads []*OverloadReference
+}
+
+// OverloadReference is an overload of a registered function.
+type OverloadReference struct {
+	ID     string
+	GoType reflect.Type
+}
+
+// Analyze returns the variables, struct fields and registered functions referenced by the checked AST.
+// Fields are reported by their full selection path only, e.g. "msg.meta.name" but not "msg.meta".
+// Only variables declared in the registry are reported, variables of comprehensions such as the m of
+// msgs.exists(m, m.meta.name == "x") and the fields selected on them are not.
+func (r *Registry) Analyze(checked *cel.Ast) (*Analysis, error) {
+	if !checked.IsChecked() {
+		return nil, fmt.Errorf("ast is not checked")
+	}
+	native := checked.NativeRep()
+	refs := native.ReferenceMap()
+
+	variables := map[string]*VariableReference{}
+	fields := map[string]*FieldReference{}
+	functions := map[string]*FunctionReference{}
+	overloads := map[string]struct{}{}
+
+	exprs := ast.MatchDescendants(ast.NavigateAST(native), func(ast.NavigableExpr) bool { return true })
+	for _, expr := range exprs {
+		if ref, ok := refs[expr.ID()]; ok && ref.Name != "" && ref.Value == nil {
+			if _, ok := r.variables[ref.Name]; !ok || isComprehensionVariable(expr, ref.Name) {
+				continue
+			}
+			if _, ok := variables[ref.Name]; !ok {
+				variables[ref.Name] = &VariableReference{
+					Name:   ref.Name,
+					Type:   native.GetType(expr.ID()),
+					GoType: r.variableTypes[ref.Name],
+				}
+			}
+			continue
+		}
+
+		switch expr.Kind() {
+		case ast.SelectKind:
+			sel := expr.AsSelect()
+			r.analyzeField(native, fields, expr, sel.Operand(), ".", sel.FieldName())
+		case ast.CallKind:
+			call := expr.AsCall()
+			if call.FunctionName() == operators.OptSelect && len(call.Args()) == 2 {
+				if name, ok := call.Args()[1].AsLiteral().(types.String); ok {
+					r.analyzeField(native, fields, expr, call.Args()[0], ".?", string(name))
+				}
+				continue
+			}
+			ref, ok := refs[expr.ID()]
+			if !ok {
+				continue
+			}
+			for _, id := range ref.OverloadIDs {
+				o, ok := r.overloads[id]
+				if !ok || o.goType() == nil {
+					continue
+				}
+				if _, ok := overloads[id]; ok {
+					continue
+				}
+				overloads[id] = struct{}{}
+				fn, ok := functions[call.FunctionName()]
+				if !ok {
+					fn = &FunctionReference{Name: call.FunctionName()}
+					functions[call.FunctionName()] = fn
+				}
+				fn.Overloads = append(fn.Overloads, &OverloadReference{
+					ID:     id,
+					GoType: o.goType(),
+				})
+			}
+		}
+	}
+
+	analysis := &Analysis{}
+	for _, v := range variables {
+		analysis.Variables = append(analysis.Variables, v)
+	}
+	sort.Slice(analysis.Variables, func(i, j int) bool {
+		return analysis.Variables[i].Name < analysis.Variables[j].Name
+	})
+	for _, f := range fields {
+		analysis.Fields = append(analysis.Fields, f)
+	}
+	sort.Slice(analysis.Fields, func(i, j int) bool {
+		return analysis.Fields[i].Path < analysis.Fields[j].Path
+	})
+	for _, fn := range functions {
+		sort.Slice(fn.Overloads, func(i, j int) bool {
+			return fn.Overloads[i].ID < fn.Overloads[j].ID
+		})
+		analysis.Functions = append(analysis.Functions, fn)
+	}
+	sort.Slice(analysis.Functions, func(i, j int) bool {
+		return analysis.Functions[i].Name < analysis.Functions[j].Name
+	})
+	return analysis, nil
+}
+
+func (r *Registry) analyzeField(native *ast.AST, fields map[string]*FieldReference, expr ast.NavigableExpr, operand ast.Expr, op, fieldName string) {
+	// Only the outermost selection of a chain is reported.
+	if parent, ok := expr.Parent(); ok && isFieldOperand(parent, expr.ID()) {
+		return
+	}
+	if root, ok := rootIdent(operand); ok && isComprehensionVariable(expr, root) {
+		return
+	}
+	operandPath, err := cel.ExprToString(operand, native.SourceInfo())
+	if err != nil {
+		return
+	}
+	path := operandPath + op + fieldName
+	if _, ok := fields[path]; ok {
+		return
+	}
+
+	structType := native.GetType(operand.ID())
+	if structType.Kind() != types.StructKind && len(structType.Parameters()) == 1 {
+		// Nullable and optional values of a struct.
+		structType = structType.Parameters()[0]
+	}
+	if structType.Kind() != types.StructKind {
+		return
+	}
+	field := &FieldReference{
+		Path:   path,
+		Struct: structType.TypeName(),
+		Field:  fieldName,
+	}
+	if fieldType, ok := r.FindStructFieldType(field.Struct, fieldName); ok {
+		field.Type = fieldType.Type
+	}
+	if t, ok := r.nativeTypeProvider.nativeTypes[field.Struct]; ok && t.GetRawType().Kind() == reflect.Struct {
+		rawType := t.GetRawType()
+		if index, ok := getStructFieldMap(rawType, t.TagName())[fieldName]; ok {
+			field.GoType = rawType.Field(index).Type
+		}
+	}
+	fields[path] = field
+}
+
+func isFieldOperand(parent ast.Expr, id int64) bool {
+	switch parent.Kind() {
+	case ast.SelectKind:
+		return parent.AsSelect().Operand().ID() == id
+	case ast.CallKind:
+		call := parent.AsCall()
+		return call.FunctionName() == operators.OptSelect && len(call.Args()) == 2 && call.Args()[0].ID() == id
+	}
+	return false
+}
+
+// rootIdent returns the identifier a chain of selections starts at.
+func rootIdent(expr ast.Expr) (string, bool) {
+	for {
+		switch expr.Kind() {
+		case ast.IdentKind:
+			return expr.AsIdent(), true
+		case ast.SelectKind:
+			expr = expr.AsSelect().Operand()
+		case ast.CallKind:
+			call := expr.AsCall()
+			if call.FunctionName() != operators.OptSelect || len(call.Args()) != 2 {
+				return "", false
+			}
+			expr = call.Args()[0]
+		default:
+			return "", false
+		}
+	}
+}
+
+// isComprehensionVariable reports whether the name refers to an iteration or accumulator variable
+// of a comprehension the expression is in the scope of.
+func isComprehensionVariable(expr ast.NavigableExpr, name string) bool {
+	child := expr
+	for parent, ok := expr.Parent(); ok; parent, ok = parent.Parent() {
+		if parent.Kind() == ast.ComprehensionKind {
+			comp := parent.AsComprehension()
+			inLoop := child.ID() == comp.LoopCondition().ID() || child.ID() == comp.LoopStep().ID()
+			if inLoop && (name == comp.IterVar() || comp.HasIterVar2() && name == comp.IterVar2()) {
+				return true
+			}
+			if (inLoop || child.ID() == comp.Result().ID()) && name == comp.AccuVar() {
+				return true
+			}
+		}
+		child = parent
+	}
+	return false
+}
@@ -0,0 +1,247 @@
+package easycel
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+)
+
+// CompatibilityReport is the result of CheckCompatibility.
+type CompatibilityReport struct {
+	// Expressions are the expressions compiling with the old registry
+	// which fail or have another output type with the new one, in corpus order.
+	Expressions []*ExpressionChange
+	// Declarations are the declarations of the old registry removed or changed in the new one.
+	Declarations []*DeclarationChange
+}
+
+// ExpressionChange is an expression of the corpus broken by the new registry.
+type ExpressionChange struct {
+	Source string
+	// OldType is the output type with the old registry.
+	OldType *cel.Type
+	// NewType is the output type with the new registry, nil if the expression fails to compile.
+	NewType *cel.Type
+	// Err is the compile error with the new registry.
+	Err error
+}
+
+// String returns the change, e.g. `"a.b": output type changed from int to string`.
+func (c *ExpressionChange) String() string {
+	if c.Err != nil {
+		return fmt.Sprintf("%q: fails to compile: %v", c.Source, c.Err)
+	}
+	return fmt.Sprintf("%q: output type changed from %s to %s", c.Source, c.OldType, c.NewType)
+}
+
+// ChangeKind is the kind of a declaration change.
+type ChangeKind string
+
+const (
+	ChangeRemovedType         ChangeKind = "removed type"
+	ChangeRemovedField        ChangeKind = "removed field"
+	ChangeRenamedField        ChangeKind = "renamed field"
+	ChangeChangedFieldType    ChangeKind = "changed field type"
+	ChangeRemovedVariable     ChangeKind = "removed variable"
+	ChangeChangedVariableType ChangeKind = "changed variable type"
+	ChangeRemovedOverload     ChangeKind = "removed overload"
+)
+
+// DeclarationChange is a declaration of the old registry removed or changed in the new one.
+type DeclarationChange struct {
+	Kind ChangeKind
+	// Name is the declaration in the old registry, e.g. "acme.Account.name" for fields,
+	// or the overload ID for overloads.
+	Name string
+	// Old and New describe the declaration before and after, e.g. the types of a field,
+	// or the field names of a renamed tag. New is empty for removals.
+	Old string
+	New string
+}
+
+// String returns the change, e.g. "changed field type acme.Account.age: int -> string".
+func (c *DeclarationChange) String() string {
+	if c.New == "" {
+		return fmt.Sprintf("%s %s: %s", c.Kind, c.Name, c.Old)
+	}
+	return fmt.Sprintf("%s %s: %s -> %s", c.Kind, c.Name, c.Old, c.New)
+}
+
+// Breaking reports whether any expression or declaration is broken by the new registry.
+func (r *CompatibilityReport) Breaking() bool {
+	return len(r.Expressions) != 0 || len(r.Declarations) != 0
+}
+
+// String returns the report with one change per line.
+func (r *CompatibilityReport) String() string {
+	var sb strings.Builder
+	for _, c := range r.Expressions {
+		fmt.Fprintf(&sb, "expression %s\n", c)
+	}
+	for _, c := range r.Declarations {
+		fmt.Fprintf(&sb, "%s\n", c)
+	}
+	return sb.String()
+}
+
+// CheckCompatibility compiles the corpus of expressions with the old and the new registry,
+// reporting the expressions broken by the new registry together with the declarations it removes or changes.
+// Expressions already failing with the old registry are not reported.
+// The options are used for the environments of both registries.
+func CheckCompatibility(oldRegistry, newRegistry *Registry, corpus []string, opts ...cel.EnvOption) (*CompatibilityReport, error) {
+	oldEnv, err := NewEnvironment(append([]cel.EnvOption{cel.Lib(oldRegistry)}, opts...)...)
+	if err != nil {
+		return nil, fmt.Errorf("old registry: %w", err)
+	}
+	newEnv, err := NewEnvironment(append([]cel.EnvOption{cel.Lib(newRegistry)}, opts...)...)
+	if err != nil {
+		return nil, fmt.Errorf("new registry: %w", err)
+	}
+
+	report := &CompatibilityReport{}
+	for _, src := range corpus {
+		oldAst, err := oldEnv.Compile(src)
+		if err != nil {
+			continue
+		}
+		change := &ExpressionChange{
+			Source:  src,
+			OldType: oldAst.OutputType(),
+		}
+		newAst, err := newEnv.Compile(src)
+		if err != nil {
+			change.Err = err
+			report.Expressions = append(report.Expressions, change)
+			continue
+		}
+		change.NewType = newAst.OutputType()
+		if change.OldType.String() != change.NewType.String() {
+			report.Expressions = append(report.Expressions, change)
+		}
+	}
+
+	report.Declarations, err = diffDeclarations(oldRegistry, newRegistry)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// diffDeclarations returns the declarations of the old registry removed or changed in the new one.
+func diffDeclarations(oldRegistry, newRegistry *Registry) ([]*DeclarationChange, error) {
+	var changes []*DeclarationChange
+
+	newTypes := map[string]*TypeInfo{}
+	for _, t := range newRegistry.Types() {
+		newTypes[t.Name] = t
+	}
+	for _, oldType := range oldRegistry.Types() {
+		newType, ok := newTypes[oldType.Name]
+		if !ok {
+			changes = append(changes, &DeclarationChange{
+				Kind: ChangeRemovedType,
+				Name: oldType.Name,
+				Old:  oldType.GoType.String(),
+			})
+			continue
+		}
+		changes = append(changes, diffFields(oldType, newType)...)
+	}
+
+	newVariables := map[string]*VariableInfo{}
+	for _, v := range newRegistry.Variables() {
+		newVariables[v.Name] = v
+	}
+	for _, oldVariable := range oldRegistry.Variables() {
+		newVariable, ok := newVariables[oldVariable.Name]
+		switch {
+		case !ok:
+			changes = append(changes, &DeclarationChange{
+				Kind: ChangeRemovedVariable,
+				Name: oldVariable.Name,
+				Old:  oldVariable.Type.String(),
+			})
+		case oldVariable.Type.String() != newVariable.Type.String():
+			changes = append(changes, &DeclarationChange{
+				Kind: ChangeChangedVariableType,
+				Name: oldVariable.Name,
+				Old:  oldVariable.Type.String(),
+				New:  newVariable.Type.String(),
+			})
+		}
+	}
+
+	oldFunctions, err := oldRegistry.Functions()
+	if err != nil {
+		return nil, fmt.Errorf("old registry: %w", err)
+	}
+	newFunctions, err := newRegistry.Functions()
+	if err != nil {
+		return nil, fmt.Errorf("new registry: %w", err)
+	}
+	newOverloads := map[string]struct{}{}
+	for _, fn := range newFunctions {
+		for _, o := range fn.Overloads {
+			newOverloads[o.ID] = struct{}{}
+		}
+	}
+	for _, fn := range oldFunctions {
+		for _, o := range fn.Overloads {
+			if _, ok := newOverloads[o.ID]; !ok {
+				changes = append(changes, &DeclarationChange{
+					Kind: ChangeRemovedOverload,
+					Name: o.ID,
+					Old:  o.Signature(),
+				})
+			}
+		}
+	}
+	return changes, nil
+}
+
+// diffFields returns the fields of the old type removed, renamed or changed in the new type,
+// fields are renamed if the same Go struct field has another name, e.g. by a changed tag.
+func diffFields(oldType, newType *TypeInfo) []*DeclarationChange {
+	var changes []*DeclarationChange
+	newFields := map[string]*FieldInfo{}
+	newGoFields := map[string]*FieldInfo{}
+	for _, field := range newType.Fields {
+		newFields[field.Name] = field
+		if field.goName != "" {
+			newGoFields[field.goName] = field
+		}
+	}
+	for _, oldField := range oldType.Fields {
+		name := oldType.Name + "." + oldField.Name
+		newField, ok := newGoFields[oldField.goName]
+		if !ok {
+			newField, ok = newFields[oldField.Name]
+		}
+		if !ok {
+			changes = append(changes, &DeclarationChange{
+				Kind: ChangeRemovedField,
+				Name: name,
+				Old:  oldField.Type.String(),
+			})
+			continue
+		}
+		if newField.Name != oldField.Name {
+			changes = append(changes, &DeclarationChange{
+				Kind: ChangeRenamedField,
+				Name: name,
+				Old:  oldField.Name,
+				New:  newField.Name,
+			})
+		}
+		if oldField.Type.String() != newField.Type.String() {
+			changes = append(changes, &DeclarationChange{
+				Kind: ChangeChangedFieldType,
+				Name: name,
+				Old:  oldField.Type.String(),
+				New:  newField.Type.String(),
+			})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,274 @@
+package easycel
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/common/types"
+)
+
+// Clone returns a copy of the registry, registrations on either one are not seen by the other.
+func (r *Registry) Clone() (*Registry, error) {
+	return r.clone(r.libraryName)
+}
+
+// Extend returns a child registry named name that inherits all registrations of the registry.
+// Variables and function overloads registered again in the child replace the inherited ones,
+// registrations on the registry after Extend are not seen by the child.
+func (r *Registry) Extend(name string) (*Registry, error) {
+	child, err := r.clone(name)
+	if err != nil {
+		return nil, err
+	}
+	for variable := range child.variables {
+		child.inheritedVariables[variable] = struct{}{}
+	}
+	for _, o := range child.overloads {
+		o.inherited = true
+	}
+	return child, nil
+}
+
+func (r *Registry) clone(name string) (*Registry, error) {
+	c := NewRegistry(name,
+		WithTagName(r.tagName),
+		WithContainer(r.container),
+		WithFieldPresence(r.presence),
+		WithCostLimit(r.costLimit),
+	)
+	registry := r.registry.Copy()
+	c.setTypeProvider(r.nativeTypeProvider.clone(registry), registry)
+	if r.adapter != r.nativeTypeProvider {
+		c.adapter = r.adapter
+	}
+	if r.provider != r.nativeTypeProvider {
+		c.provider = r.provider
+	}
+	c.refTypes = append(c.refTypes, r.refTypes...)
+
+	for name, typ := range r.variables {
+		c.variables[name] = typ
+	}
+	for name, typ := range r.variableTypes {
+		c.variableTypes[name] = typ
+	}
+	for name, doc := range r.variableDocs {
+		c.variableDocs[name] = doc
+	}
+	for name := range r.inheritedVariables {
+		c.inheritedVariables[name] = struct{}{}
+	}
+	for name, resolver := range r.lazyVariables {
+		c.lazyVariables[name] = resolver
+	}
+	c.inputType = r.inputType
+	c.inputFields = r.inputFields
+
+	for _, name := range sortedKeys(r.funcs) {
+		for _, o := range r.funcs[name].overloads {
+			err := c.addCopy(name, o)
+			if err != nil {
+				return nil, fmt.Errorf("clone %s: %w", o, err)
+			}
+		}
+	}
+	return c, nil
+}
+
+// addCopy adds a copy of the overload of another registry,
+// Go funcs are registered again to convert their results with the adapter of the registry.
+// The copy keeps the ID and argument types of the overload, which types renamed after its registration don't change.
+func (r *Registry) addCopy(name string, o *overload) error {
+	if !o.fun.IsValid() {
+		copied := *o
+		copied.origin = o.registered()
+		return r.addOverload(name, &copied)
+	}
+	config := o.config
+	config.overloadID = o.id
+	rebound, err := r.newFuncOverload(name, o.fun, o.member, config)
+	if err != nil {
+		return err
+	}
+	rebound.config = o.config
+	rebound.argTypes = o.argTypes
+	rebound.inherited = o.inherited
+	rebound.origin = o.registered()
+	return r.addOverload(name, rebound)
+}
+
+// Merge returns a registry named name combining the registrations of the registries,
+// so that they can be used together in one environment.
+// Registrations made on several of the registries, e.g. inherited from a common registry, are merged once,
+// while conflicting registrations and options are reported as errors.
+// Registries with a custom type adapter or provider can only be the first one.
+func Merge(name string, registries ...*Registry) (*Registry, error) {
+	if len(registries) == 0 {
+		return NewRegistry(name), nil
+	}
+	merged, err := registries[0].clone(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, other := range registries[1:] {
+		err := merged.merge(other)
+		if err != nil {
+			return nil, fmt.Errorf("merge %s: %w", other.libraryName, err)
+		}
+	}
+	return merged, nil
+}
+
+func (r *Registry) merge(other *Registry) error {
+	if other.adapter != other.nativeTypeProvider || other.provider != other.nativeTypeProvider {
+		return fmt.Errorf("custom type adapter or provider can't be merged")
+	}
+	if r.tagName != other.tagName {
+		return fmt.Errorf("tag name %q conflicts with %q", other.tagName, r.tagName)
+	}
+	if r.presence != other.presence {
+		return fmt.Errorf("field presence %d conflicts with %d", other.presence, r.presence)
+	}
+	if other.container != "" {
+		if r.container != "" && r.container != other.container {
+			return fmt.Errorf("container %q conflicts with %q", other.container, r.container)
+		}
+		r.container = other.container
+	}
+	if other.costLimit != 0 {
+		if r.costLimit != 0 && r.costLimit != other.costLimit {
+			return fmt.Errorf("cost limit %d conflicts with %d", other.costLimit, r.costLimit)
+		}
+		r.costLimit = other.costLimit
+	}
+	if other.inputType != nil {
+		if r.inputType != nil && r.inputType != other.inputType {
+			return fmt.Errorf("input %v conflicts with %v", other.inputType, r.inputType)
+		}
+		r.inputType = other.inputType
+		r.inputFields = other.inputFields
+	}
+
+	for _, t := range other.refTypes {
+		if r.hasRefType(t.TypeName()) {
+			continue
+		}
+		err := r.registerRefType(t)
+		if err != nil {
+			return err
+		}
+	}
+	err := r.nativeTypeProvider.merge(other.nativeTypeProvider)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range sortedKeys(other.variables) {
+		typ := other.variables[name]
+		if existing, ok := r.variables[name]; ok {
+			if existing.String() != typ.String() || r.variableTypes[name] != other.variableTypes[name] {
+				return fmt.Errorf("variable %s of type %s conflicts with type %s", name, typ, existing)
+			}
+			continue
+		}
+		r.variables[name] = typ
+		if goType, ok := other.variableTypes[name]; ok {
+			r.variableTypes[name] = goType
+		}
+		if doc, ok := other.variableDocs[name]; ok {
+			r.variableDocs[name] = doc
+		}
+		if resolver, ok := other.lazyVariables[name]; ok {
+			r.lazyVariables[name] = resolver
+		}
+	}
+
+	for _, name := range sortedKeys(other.funcs) {
+		for _, o := range other.funcs[name].overloads {
+			if existing, ok := r.overloads[o.id]; ok {
+				if !sameOverload(existing, o) {
+					return fmt.Errorf("overload %s of %s conflicts with an existing overload", o.id, name)
+				}
+				continue
+			}
+			err := r.addCopy(name, o)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Registry) hasRefType(name string) bool {
+	for _, t := range r.refTypes {
+		if t.TypeName() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// sameOverload reports whether both overloads are copies of the same registered overload,
+// e.g. inherited from a common registry, or are the same operator of a registered CEL value.
+// Overloads registered separately conflict even from the same Go func, as closures may capture different state.
+func sameOverload(a, b *overload) bool {
+	if !a.fun.IsValid() && !b.fun.IsValid() {
+		return a.id == b.id
+	}
+	return a.registered() == b.registered()
+}
+
+// clone returns a copy of the provider resolving CEL types with the registry.
+func (tp *nativeTypeProvider) clone(registry *types.Registry) *nativeTypeProvider {
+	c := newNativeTypeProvider(tp.tagName, registry, registry)
+	c.presence = tp.presence
+	for k, v := range tp.conversions {
+		c.conversions[k] = v
+	}
+	for k, v := range tp.reverseConversions {
+		c.reverseConversions[k] = v
+	}
+	for k, v := range tp.nativeTypes {
+		c.nativeTypes[k] = v
+	}
+	for k, v := range tp.typeNames {
+		c.typeNames[k] = v
+	}
+	return c
+}
+
+// merge adds the types and conversions of the other provider.
+func (tp *nativeTypeProvider) merge(other *nativeTypeProvider) error {
+	for _, name := range sortedKeys(other.nativeTypes) {
+		t := other.nativeTypes[name]
+		if existing, ok := tp.nativeTypes[name]; ok {
+			if existing.GetRawType() != t.GetRawType() {
+				return fmt.Errorf("type %s of %v conflicts with %v", name, t.GetRawType(), existing.GetRawType())
+			}
+			continue
+		}
+		if existing, ok := tp.typeNames[t.GetRawType()]; ok {
+			return fmt.Errorf("type %v registered as %s conflicts with %s", t.GetRawType(), name, existing)
+		}
+		tp.nativeTypes[name] = t
+		tp.typeNames[t.GetRawType()] = name
+	}
+	for k, v := range other.conversions {
+		if existing, ok := tp.conversions[k]; ok && !sameConversion(existing, v) {
+			return fmt.Errorf("conversion of %v conflicts with an existing conversion", k)
+		}
+		tp.conversions[k] = v
+	}
+	for k, v := range other.reverseConversions {
+		if existing, ok := tp.reverseConversions[k]; ok && !sameConversion(existing, v) {
+			return fmt.Errorf("conversion to %v conflicts with an existing conversion", k)
+		}
+		tp.reverseConversions[k] = v
+	}
+	clear(tp.typeValues)
+	return nil
+}
+
+func sameConversion(a, b *convertType) bool {
+	return a.targetType == b.targetType && a.convertFunc.Pointer() == b.convertFunc.Pointer()
+}
@@ -0,0 +1,85 @@
+package easycel
+
+import (
+	"math"
+
+	"github.com/google/cel-go/checker"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+)
+
+// functionCost is the cost of a call, a fixed cost plus a cost per unit of the size of
+// the sized arguments (strings, bytes, lists and maps).
+type functionCost struct {
+	fixed   uint64
+	perSize float64
+}
+
+// estimate implements the checker.FunctionEstimator.
+func (c *functionCost) estimate(estimator checker.CostEstimator, target *checker.AstNode, args []checker.AstNode) *checker.CallEstimate {
+	cost := checker.FixedCostEstimate(c.fixed)
+	if c.perSize != 0 {
+		nodes := args
+		if target != nil {
+			nodes = append([]checker.AstNode{*target}, args...)
+		}
+		size := checker.FixedSizeEstimate(0)
+		for _, node := range nodes {
+			if !isSizedType(node.Type()) {
+				continue
+			}
+			nodeSize := node.ComputedSize()
+			if nodeSize == nil {
+				nodeSize = estimator.EstimateSize(node)
+			}
+			if nodeSize == nil {
+				unknown := checker.UnknownSizeEstimate()
+				nodeSize = &unknown
+			}
+			size = size.Add(*nodeSize)
+		}
+		cost = cost.Add(size.MultiplyByCostFactor(c.perSize))
+	}
+	return &checker.CallEstimate{CostEstimate: cost}
+}
+
+// track implements the interpreter.FunctionTracker.
+func (c *functionCost) track(args []ref.Val, result ref.Val) *uint64 {
+	cost := c.fixed
+	if c.perSize != 0 {
+		var size int64
+		for _, arg := range args {
+			sizer, ok := arg.(traits.Sizer)
+			if !ok {
+				continue
+			}
+			if n, ok := sizer.Size().(types.Int); ok {
+				size += int64(n)
+			}
+		}
+		cost += uint64(math.Ceil(float64(size) * c.perSize))
+	}
+	return &cost
+}
+
+func isSizedType(typ *types.Type) bool {
+	switch typ.Kind() {
+	case types.StringKind, types.BytesKind, types.ListKind, types.MapKind:
+		return true
+	}
+	return false
+}
+
+// sizeEstimator leaves all size estimates to the defaults of the checker.
+type sizeEstimator struct{}
+
+// EstimateSize implements the checker.CostEstimator interface method.
+func (sizeEstimator) EstimateSize(element checker.AstNode) *checker.SizeEstimate {
+	return nil
+}
+
+// EstimateCallCost implements the checker.CostEstimator interface method.
+func (sizeEstimator) EstimateCallCost(function, overloadID string, target *checker.AstNode, args []checker.AstNode) *checker.CallEstimate {
+	return nil
+}
@@ -0,0 +1,237 @@
+package easycel
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+)
+
+// Decode converts the CEL value into out, which must be a non-nil pointer.
+// Lists, maps and structs are walked recursively, structs are filled by the field names
+// of their tags from CEL maps and native structs of other Go types.
+// Errors are qualified with the path of the failed value, e.g. "list[3].meta.name: expected string, got int".
+// Struct fields are named by the tag name of the native structs being decoded, or else "easycel".
+func Decode(val ref.Val, out any) error {
+	return decode(nil, val, out)
+}
+
+// Decode is Decode using the tag name and the reverse conversions registered with the registry.
+func (r *Registry) Decode(val ref.Val, out any) error {
+	return decode(r.nativeTypeProvider, val, out)
+}
+
+func decode(tp *nativeTypeProvider, val ref.Val, out any) error {
+	dst := reflect.ValueOf(out)
+	if dst.Kind() != reflect.Pointer || dst.IsNil() {
+		return fmt.Errorf("decode target must be a non-nil pointer, got %T", out)
+	}
+	d := &decoder{tp: tp}
+	return d.decode("", val, dst.Elem())
+}
+
+type decoder struct {
+	// tp is nil if the values are decoded without registry,
+	// the provider of the native structs is used instead.
+	tp *nativeTypeProvider
+}
+
+func (d *decoder) provider(val ref.Val) *nativeTypeProvider {
+	if d.tp != nil {
+		return d.tp
+	}
+	if obj, ok := val.(*structObject); ok {
+		return obj.tp
+	}
+	return defaultTypeProvider
+}
+
+func (d *decoder) decode(path string, val ref.Val, dst reflect.Value) error {
+	if err, ok := val.(*types.Err); ok {
+		return d.errorf(path, "%v", err)
+	}
+	typ := dst.Type()
+
+	if conv, ok := d.provider(val).reverseConversions[typ]; ok && reflect.TypeOf(val).AssignableTo(conv.targetType) {
+		out := conv.convertFunc.Call([]reflect.Value{reflect.ValueOf(val)})
+		dst.Set(out[0])
+		return nil
+	}
+
+	if val.Type() == types.NullType {
+		switch typ.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+			dst.Set(reflect.Zero(typ))
+			return nil
+		}
+		return d.mismatch(path, val, typ)
+	}
+
+	if native := reflect.ValueOf(val.Value()); native.IsValid() && native.Type() == typ {
+		dst.Set(native)
+		return nil
+	}
+
+	switch typ.Kind() {
+	case reflect.Pointer:
+		elem := reflect.New(typ.Elem())
+		err := d.decode(path, val, elem.Elem())
+		if err != nil {
+			return err
+		}
+		dst.Set(elem)
+		return nil
+	case reflect.Interface:
+		if typ.NumMethod() == 0 {
+			return d.decodeAny(path, val, dst)
+		}
+	case reflect.Slice:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		list, ok := val.(traits.Lister)
+		if !ok {
+			return d.mismatch(path, val, typ)
+		}
+		size := int(list.Size().(types.Int))
+		out := reflect.MakeSlice(typ, size, size)
+		for i := 0; i < size; i++ {
+			err := d.decode(fmt.Sprintf("%s[%d]", path, i), list.Get(types.Int(i)), out.Index(i))
+			if err != nil {
+				return err
+			}
+		}
+		dst.Set(out)
+		return nil
+	case reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		list, ok := val.(traits.Lister)
+		if !ok {
+			return d.mismatch(path, val, typ)
+		}
+		size := int(list.Size().(types.Int))
+		if size != typ.Len() {
+			return d.errorf(path, "expected %v, got list of length %d", typ, size)
+		}
+		for i := 0; i < size; i++ {
+			err := d.decode(fmt.Sprintf("%s[%d]", path, i), list.Get(types.Int(i)), dst.Index(i))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		mapper, ok := val.(traits.Mapper)
+		if !ok {
+			break
+		}
+		out := reflect.MakeMapWithSize(typ, int(mapper.Size().(types.Int)))
+		for it := mapper.Iterator(); it.HasNext() == types.True; {
+			key := it.Next()
+			keyPath := fmt.Sprintf("%s[%v]", path, key.Value())
+			outKey := reflect.New(typ.Key()).Elem()
+			err := d.decode(keyPath, key, outKey)
+			if err != nil {
+				return err
+			}
+			outVal := reflect.New(typ.Elem()).Elem()
+			err = d.decode(keyPath, mapper.Get(key), outVal)
+			if err != nil {
+				return err
+			}
+			out.SetMapIndex(outKey, outVal)
+		}
+		dst.Set(out)
+		return nil
+	case reflect.Struct:
+		if typ == timestampType {
+			break
+		}
+		switch v := val.(type) {
+		case *structObject:
+			if native, err := v.ConvertToNative(typ); err == nil {
+				dst.Set(reflect.ValueOf(native))
+				return nil
+			}
+			return d.decodeStruct(path, val, dst, func(name string) (ref.Val, bool) {
+				if _, ok := getStructFieldMap(reflect.Indirect(v.refValue).Type(), v.tp.tagName)[name]; !ok {
+					return nil, false
+				}
+				return v.Get(types.String(name)), true
+			})
+		case traits.Mapper:
+			return d.decodeStruct(path, val, dst, func(name string) (ref.Val, bool) {
+				return v.Find(types.String(name))
+			})
+		}
+	}
+
+	native, err := val.ConvertToNative(typ)
+	if err != nil {
+		return d.mismatch(path, val, typ)
+	}
+	dst.Set(reflect.ValueOf(native))
+	return nil
+}
+
+// decodeStruct fills the exported fields of the struct by their tag names,
+// fields missing from the value are left unchanged.
+func (d *decoder) decodeStruct(path string, val ref.Val, dst reflect.Value, get func(name string) (ref.Val, bool)) error {
+	tagName := d.provider(val).tagName
+	typ := dst.Type()
+	for _, name := range getStructFields(typ, tagName) {
+		fieldVal, ok := get(name)
+		if !ok {
+			continue
+		}
+		index := getStructFieldMap(typ, tagName)[name]
+		fieldPath := name
+		if path != "" {
+			fieldPath = path + "." + name
+		}
+		err := d.decode(fieldPath, fieldVal, dst.Field(index))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeAny decodes lists and maps into []any and map[string]any,
+// other values into their native Go value.
+// Maps with keys other than strings can't be decoded into any.
+func (d *decoder) decodeAny(path string, val ref.Val, dst reflect.Value) error {
+	var out reflect.Value
+	switch val.(type) {
+	case traits.Lister:
+		out = reflect.New(reflect.TypeOf([]any{})).Elem()
+	case traits.Mapper:
+		out = reflect.New(reflect.TypeOf(map[string]any{})).Elem()
+	default:
+		if native := reflect.ValueOf(val.Value()); native.IsValid() {
+			dst.Set(native)
+		}
+		return nil
+	}
+	err := d.decode(path, val, out)
+	if err != nil {
+		return err
+	}
+	dst.Set(out)
+	return nil
+}
+
+func (d *decoder) mismatch(path string, val ref.Val, typ reflect.Type) error {
+	return d.errorf(path, "expected %v, got %s", typ, val.Type().TypeName())
+}
+
+func (d *decoder) errorf(path string, format string, args ...any) error {
+	if path == "" {
+		return fmt.Errorf(format, args...)
+	}
+	return fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...))
+}
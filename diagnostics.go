@@ -0,0 +1,324 @@
+package easycel
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker"
+	"github.com/google/cel-go/common"
+	"github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/types"
+)
+
+// Severity is the severity of a diagnostic.
+type Severity string
+
+const (
+	// SeverityError is a diagnostic which prevents the source from compiling.
+	SeverityError Severity = "error"
+)
+
+// Diagnostic is a single problem found while compiling the source.
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	// Offset is the byte offset in the source, Line is 1-based and Column 0-based.
+	Offset int `json:"offset"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	// Suggestion is the replacement proposed for an unknown name, if any.
+	Suggestion string `json:"suggestion,omitempty"`
+
+	display string
+}
+
+// String returns the diagnostic with the source line it points to.
+func (d *Diagnostic) String() string {
+	return d.display
+}
+
+// Diagnostics is the error returned when the source fails to compile.
+type Diagnostics struct {
+	Items []*Diagnostic `json:"items"`
+}
+
+// Error implements the error interface.
+func (d *Diagnostics) Error() string {
+	out := make([]string, 0, len(d.Items))
+	for _, item := range d.Items {
+		out = append(out, item.display)
+	}
+	return strings.Join(out, "\n")
+}
+
+// JSON returns the diagnostics as indented JSON.
+func (d *Diagnostics) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+var (
+	undefinedFieldPattern      = regexp.MustCompile(`^undefined field '([^']*)'`)
+	undeclaredReferencePattern = regexp.MustCompile(`^undeclared reference to '([^']*)'`)
+)
+
+// newDiagnostics converts the issues of the parsed AST into diagnostics,
+// the parsed AST is used to suggest names and may be nil.
+func (e *Environment) newDiagnostics(source common.Source, parsed *cel.Ast, issues *cel.Issues) *Diagnostics {
+	diags := &Diagnostics{}
+	var s *suggester
+	if parsed != nil {
+		s = &suggester{env: e, source: source, parsed: parsed}
+	}
+	for _, issue := range issues.Errors() {
+		diag := &Diagnostic{
+			Severity: SeverityError,
+			Message:  issue.Message,
+			Line:     issue.Location.Line(),
+			Column:   issue.Location.Column(),
+		}
+		if offset, ok := source.LocationOffset(issue.Location); ok {
+			diag.Offset = byteOffset(source.Content(), int(offset))
+		}
+		if s != nil {
+			diag.Suggestion = s.suggest(issue)
+		}
+
+		message := issue.Message
+		if diag.Suggestion != "" {
+			message += ", did you mean '" + diag.Suggestion + "'?"
+		}
+		diag.display = (&common.Error{
+			Location: issue.Location,
+			Message:  message,
+			ExprID:   issue.ExprID,
+		}).ToDisplayString(source)
+		diags.Items = append(diags.Items, diag)
+	}
+	return diags
+}
+
+// newExprDiagnostics returns diagnostics of the message pointing at the start of the expression of the checked AST.
+func newExprDiagnostics(source common.Source, checked *cel.Ast, id int64, message string) *Diagnostics {
+	info := checked.NativeRep().SourceInfo()
+	location := info.GetStartLocation(id)
+	if expr := findExpr(checked, id); expr != nil {
+		// Calls are positioned at the function or operator, the expression starts at its leftmost sub-expression.
+		for _, desc := range ast.MatchDescendants(expr, func(ast.NavigableExpr) bool { return true }) {
+			loc := info.GetStartLocation(desc.ID())
+			if loc.Line() > 0 && (loc.Line() < location.Line() ||
+				loc.Line() == location.Line() && loc.Column() < location.Column()) {
+				location = loc
+			}
+		}
+	}
+	diag := &Diagnostic{
+		Severity: SeverityError,
+		Message:  message,
+		Line:     location.Line(),
+		Column:   location.Column(),
+	}
+	if offset, ok := source.LocationOffset(location); ok {
+		diag.Offset = byteOffset(source.Content(), int(offset))
+	}
+	diag.display = (&common.Error{
+		Location: location,
+		Message:  message,
+		ExprID:   id,
+	}).ToDisplayString(source)
+	return &Diagnostics{Items: []*Diagnostic{diag}}
+}
+
+// suggester suggests names for the issues of a parsed AST.
+type suggester struct {
+	env    *Environment
+	source common.Source
+	parsed *cel.Ast
+	// checked is the AST checked despite its issues, created on first use.
+	checked     *ast.AST
+	checkedOnce bool
+}
+
+// suggest returns the closest known name for an unknown field, function, type or variable.
+func (s *suggester) suggest(issue *cel.Error) string {
+	if match := undefinedFieldPattern.FindStringSubmatch(issue.Message); match != nil {
+		return closestName(match[1], s.fieldCandidates(issue.ExprID))
+	}
+	if match := undeclaredReferencePattern.FindStringSubmatch(issue.Message); match != nil {
+		expr := findExpr(s.parsed, issue.ExprID)
+		switch {
+		case expr != nil && expr.Kind() == ast.CallKind:
+			return closestName(match[1], s.env.functionCandidates())
+		case expr != nil && expr.Kind() == ast.StructKind:
+			return closestName(match[1], s.env.typeCandidates())
+		}
+		return closestName(match[1], s.env.variableCandidates())
+	}
+	return ""
+}
+
+// fieldCandidates returns the field names of the struct selected or created by the expression.
+func (s *suggester) fieldCandidates(id int64) []string {
+	var structID int64
+	if expr := findExpr(s.parsed, id); expr != nil && expr.Kind() == ast.SelectKind {
+		structID = expr.AsSelect().Operand().ID()
+	} else {
+		structs := ast.MatchDescendants(ast.NavigateAST(s.parsed.NativeRep()), ast.KindMatcher(ast.StructKind))
+		for _, expr := range structs {
+			for _, field := range expr.AsStruct().Fields() {
+				if field.ID() == id {
+					structID = expr.ID()
+				}
+			}
+		}
+	}
+	checked := s.check()
+	if structID == 0 || checked == nil {
+		return nil
+	}
+
+	typ := checked.GetType(structID)
+	if typ.Kind() != types.StructKind && len(typ.Parameters()) == 1 {
+		typ = typ.Parameters()[0]
+	}
+	if typ.Kind() != types.StructKind {
+		return nil
+	}
+	names, _ := s.env.env.CELTypeProvider().FindStructFieldNames(typ.TypeName())
+	return names
+}
+
+// check returns the AST checked despite its issues, with the types of the sub-expressions the checker resolved,
+// e.g. of the variables of comprehensions.
+func (s *suggester) check() *ast.AST {
+	if s.checkedOnce {
+		return s.checked
+	}
+	s.checkedOnce = true
+	env := s.env.env
+	chk, err := checker.NewEnv(env.Container, env.CELTypeProvider())
+	if err != nil {
+		return nil
+	}
+	err = chk.AddIdents(env.Variables()...)
+	if err != nil {
+		return nil
+	}
+	for _, fn := range env.Functions() {
+		if fn.IsDeclarationDisabled() {
+			continue
+		}
+		err = chk.AddFunctions(fn)
+		if err != nil {
+			return nil
+		}
+	}
+	// The checker resolves names in place, the parsed AST is kept for finding the issues.
+	s.checked, _ = checker.Check(ast.Copy(s.parsed.NativeRep()), s.source, chk)
+	return s.checked
+}
+
+func (e *Environment) functionCandidates() []string {
+	names := []string{}
+	for name := range e.env.Functions() {
+		// Operators are not spelled by name in the source.
+		if isOperatorName(name) {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// typeCandidates returns the names of the registered structs, also relative to the container.
+func (e *Environment) typeCandidates() []string {
+	registry := e.registry()
+	if registry == nil {
+		return nil
+	}
+	prefix := e.env.Container.Name() + "."
+	names := []string{}
+	for _, t := range registry.Types() {
+		if t.GoType.Kind() != reflect.Struct {
+			continue
+		}
+		names = append(names, t.Name)
+		if name, ok := strings.CutPrefix(t.Name, prefix); ok && prefix != "." {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (e *Environment) variableCandidates() []string {
+	names := []string{}
+	for _, v := range e.env.Variables() {
+		names = append(names, v.Name())
+	}
+	return names
+}
+
+func findExpr(parsed *cel.Ast, id int64) ast.NavigableExpr {
+	matches := ast.MatchDescendants(ast.NavigateAST(parsed.NativeRep()), func(expr ast.NavigableExpr) bool {
+		return expr.ID() == id
+	})
+	if len(matches) == 0 {
+		return nil
+	}
+	return matches[0]
+}
+
+// closestName returns the candidate with the smallest edit distance to the name,
+// or an empty string if none is close enough.
+func closestName(name string, candidates []string) string {
+	limit := len(name)/3 + 1
+	best, bestDistance := "", limit+1
+	for _, candidate := range candidates {
+		distance := editDistance(strings.ToLower(name), strings.ToLower(candidate))
+		if distance < bestDistance || (distance == bestDistance && candidate < best) {
+			best = candidate
+			bestDistance = distance
+		}
+	}
+	return best
+}
+
+// editDistance returns the edit distance between a and b,
+// counting insertions, deletions, substitutions and transpositions of adjacent runes.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+// byteOffset converts an offset in runes into an offset in bytes.
+func byteOffset(src string, runes int) int {
+	for i := range src {
+		if runes == 0 {
+			return i
+		}
+		runes--
+	}
+	return len(src)
+}
@@ -0,0 +1,174 @@
+package easycel
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Documentation is the reference of the functions, variables and types a registry exposes to expressions.
+type Documentation struct {
+	Name      string         `json:"name"`
+	Functions []*FunctionDoc `json:"functions"`
+	Variables []*VariableDoc `json:"variables"`
+	Types     []*TypeDoc     `json:"types"`
+}
+
+// FunctionDoc documents a function or method with its overloads.
+type FunctionDoc struct {
+	Name      string         `json:"name"`
+	Overloads []*OverloadDoc `json:"overloads"`
+}
+
+// OverloadDoc documents an overload by its CEL signature.
+type OverloadDoc struct {
+	ID          string   `json:"id"`
+	Signature   string   `json:"signature"`
+	Description string   `json:"description,omitempty"`
+	Examples    []string `json:"examples,omitempty"`
+	Deprecated  string   `json:"deprecated,omitempty"`
+}
+
+// VariableDoc documents a variable.
+type VariableDoc struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+}
+
+// TypeDoc documents a registered type with its fields.
+type TypeDoc struct {
+	Name            string      `json:"name"`
+	Implementations []string    `json:"implementations,omitempty"`
+	Fields          []*FieldDoc `json:"fields"`
+}
+
+// FieldDoc documents a field by its tag-derived name.
+type FieldDoc struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+}
+
+// Documentation returns the reference of the registry,
+// operators are left out as they are not called by name.
+func (r *Registry) Documentation() (*Documentation, error) {
+	functions, err := r.Functions()
+	if err != nil {
+		return nil, err
+	}
+	doc := &Documentation{
+		Name:      r.libraryName,
+		Functions: []*FunctionDoc{},
+		Variables: []*VariableDoc{},
+		Types:     []*TypeDoc{},
+	}
+	for _, fn := range functions {
+		if isOperatorName(fn.Name) {
+			continue
+		}
+		fnDoc := &FunctionDoc{
+			Name: fn.Name,
+		}
+		for _, o := range fn.Overloads {
+			fnDoc.Overloads = append(fnDoc.Overloads, &OverloadDoc{
+				ID:          o.ID,
+				Signature:   o.Signature(),
+				Description: o.Description,
+				Examples:    o.Examples,
+				Deprecated:  o.Deprecated,
+			})
+		}
+		doc.Functions = append(doc.Functions, fnDoc)
+	}
+	for _, v := range r.Variables() {
+		doc.Variables = append(doc.Variables, &VariableDoc{
+			Name:        v.Name,
+			Type:        v.Type.String(),
+			Description: v.Doc,
+		})
+	}
+	for _, t := range r.Types() {
+		typeDoc := &TypeDoc{
+			Name:            t.Name,
+			Implementations: t.Implementations,
+			Fields:          []*FieldDoc{},
+		}
+		for _, field := range t.Fields {
+			typeDoc.Fields = append(typeDoc.Fields, &FieldDoc{
+				Name:        field.Name,
+				Type:        field.Type.String(),
+				Description: field.Doc,
+			})
+		}
+		doc.Types = append(doc.Types, typeDoc)
+	}
+	return doc, nil
+}
+
+// JSON returns the documentation as indented JSON.
+func (d *Documentation) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+// Markdown returns the documentation as Markdown.
+func (d *Documentation) Markdown() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n", d.Name)
+
+	if len(d.Functions) != 0 {
+		sb.WriteString("\n## Functions\n")
+		for _, fn := range d.Functions {
+			fmt.Fprintf(&sb, "\n### %s\n\n", fn.Name)
+			for _, o := range fn.Overloads {
+				fmt.Fprintf(&sb, "- `%s`", o.Signature)
+				if o.Description != "" {
+					fmt.Fprintf(&sb, ": %s", o.Description)
+				}
+				sb.WriteString("\n")
+				if o.Deprecated != "" {
+					fmt.Fprintf(&sb, "  - **Deprecated:** %s\n", o.Deprecated)
+				}
+				for _, example := range o.Examples {
+					fmt.Fprintf(&sb, "  - Example: `%s`\n", example)
+				}
+			}
+		}
+	}
+
+	if len(d.Variables) != 0 {
+		sb.WriteString("\n## Variables\n\n")
+		sb.WriteString("| Name | Type | Description |\n")
+		sb.WriteString("| --- | --- | --- |\n")
+		for _, v := range d.Variables {
+			fmt.Fprintf(&sb, "| `%s` | `%s` | %s |\n", v.Name, v.Type, markdownCell(v.Description))
+		}
+	}
+
+	if len(d.Types) != 0 {
+		sb.WriteString("\n## Types\n")
+		for _, t := range d.Types {
+			fmt.Fprintf(&sb, "\n### %s\n\n", t.Name)
+			if len(t.Implementations) != 0 {
+				fmt.Fprintf(&sb, "Implemented by `%s`.\n\n", strings.Join(t.Implementations, "`, `"))
+			}
+			sb.WriteString("| Field | Type | Description |\n")
+			sb.WriteString("| --- | --- | --- |\n")
+			for _, field := range t.Fields {
+				fmt.Fprintf(&sb, "| `%s` | `%s` | %s |\n", field.Name, field.Type, markdownCell(field.Description))
+			}
+		}
+	}
+	return sb.String()
+}
+
+// markdownCell escapes the text for a Markdown table cell.
+func markdownCell(text string) string {
+	text = strings.ReplaceAll(text, "|", "\\|")
+	return strings.ReplaceAll(text, "\n", " ")
+}
+
+// isOperatorName reports whether the function is an operator, e.g. "_+_" or "@in".
+func isOperatorName(name string) bool {
+	return strings.HasPrefix(name, "_") || strings.HasPrefix(name, "@")
+}
@@ -1,10 +1,15 @@
 package easycel
 
 import (
+	"context"
 	"fmt"
+	"reflect"
+	"sync"
 
 	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker"
 	"github.com/google/cel-go/common"
+	"github.com/google/cel-go/common/types/ref"
 )
 
 var (
@@ -14,6 +19,11 @@ var (
 // Environment is a wrapper around CEL environment
 type Environment struct {
 	env *cel.Env
+
+	// explain is the environment tracking macro calls, created on first use by Explain.
+	explainOnce sync.Once
+	explain     *Environment
+	explainErr  error
 }
 
 // NewEnvironment creates a new CEL environment
@@ -27,19 +37,99 @@ func NewEnvironment(opts ...cel.EnvOption) (*Environment, error) {
 	}, nil
 }
 
-// Program creates a new CEL program
-func (e *Environment) Program(src string, opts ...cel.ProgramOption) (cel.Program, error) {
+// CompileOption configures how the source is compiled
+type CompileOption func(*compileConfig)
+
+type compileConfig struct {
+	expectType reflect.Type
+}
+
+// Compile parses and checks the source into a checked CEL AST,
+// compile errors are returned as *Diagnostics
+func (e *Environment) Compile(src string, opts ...CompileOption) (*cel.Ast, error) {
+	var config compileConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+
 	if src == "" {
 		return nil, errNoSourceCode
 	}
 	source := common.NewStringSource(src, "")
-	ast, issue := e.env.ParseSource(source)
+	parsed, issue := e.env.ParseSource(source)
 	if issue != nil && issue.Err() != nil {
-		return nil, issue.Err()
+		return nil, e.newDiagnostics(source, nil, issue)
 	}
-	ast, issue = e.env.Check(ast)
+	checked, issue := e.env.Check(parsed)
 	if issue != nil && issue.Err() != nil {
-		return nil, issue.Err()
+		return nil, e.newDiagnostics(source, parsed, issue)
+	}
+	if config.expectType != nil {
+		err := e.checkOutputType(source, checked, config.expectType)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return checked, nil
+}
+
+// Program creates a new CEL program
+func (e *Environment) Program(src string, opts ...cel.ProgramOption) (cel.Program, error) {
+	ast, err := e.Compile(src)
+	if err != nil {
+		return nil, err
+	}
+	return e.ProgramFromAst(ast, opts...)
+}
+
+// ProgramFromAst creates a new CEL program from a checked AST returned by Compile
+func (e *Environment) ProgramFromAst(ast *cel.Ast, opts ...cel.ProgramOption) (cel.Program, error) {
+	opts = append([]cel.ProgramOption{cel.CustomDecorator(decorateFunctionErrors)}, opts...)
+	prg, err := e.env.Program(ast, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &program{Program: prg, ast: ast, registry: e.registry()}, nil
+}
+
+// registry returns the registry providing the types of the environment, nil if there is none
+func (e *Environment) registry() *Registry {
+	r, _ := e.env.CELTypeProvider().(*Registry)
+	return r
+}
+
+// program positions the evaluation errors of a CEL program in its source,
+// and converts its results with the registry of the environment
+type program struct {
+	cel.Program
+	ast      *cel.Ast
+	registry *Registry
+}
+
+// Eval evaluates the program, see cel.Program
+func (p *program) Eval(input any) (ref.Val, *cel.EvalDetails, error) {
+	val, details, err := p.Program.Eval(input)
+	if err != nil {
+		err = newEvalError(p.ast, err)
+	}
+	return val, details, err
+}
+
+// ContextEval evaluates the program with a context, see cel.Program
+func (p *program) ContextEval(ctx context.Context, input any) (ref.Val, *cel.EvalDetails, error) {
+	val, details, err := p.Program.ContextEval(ctx, input)
+	if err != nil {
+		err = newEvalError(p.ast, err)
+	}
+	return val, details, err
+}
+
+// EstimateCost statically estimates the cost of evaluating the source,
+// including the costs registered for Go functions
+func (e *Environment) EstimateCost(src string) (checker.CostEstimate, error) {
+	ast, err := e.Compile(src)
+	if err != nil {
+		return checker.CostEstimate{}, err
 	}
-	return e.env.Program(ast, opts...)
+	return e.env.EstimateCost(ast, sizeEstimator{})
 }
@@ -1,13 +1,19 @@
 package easycel_test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/ast"
 	"github.com/google/cel-go/common/types"
 	"github.com/google/cel-go/common/types/ref"
 	"github.com/google/cel-go/common/types/traits"
@@ -18,8 +24,11 @@ import (
 func TestAll(t *testing.T) {
 	tests := []struct {
 		src         string
+		opts        []easycel.RegistryOption
 		conversions []any
 		types       []any
+		namedTypes  map[string]any
+		interfaces  map[any][]any
 		funcs       map[string][]any
 		methods     map[string][]any
 		vars        map[string]any
@@ -47,6 +56,122 @@ func TestAll(t *testing.T) {
 			},
 			want: types.String("world"),
 		},
+		{
+			src:   "msg.next.next",
+			types: []any{Message{}},
+			vars: map[string]any{
+				"msg": Message{},
+			},
+			want: types.NullValue,
+		},
+		{
+			src:   "msg.next == null",
+			types: []any{Message{}},
+			vars: map[string]any{
+				"msg": Message{},
+			},
+			want: types.True,
+		},
+		{
+			src:   "has(msg.next)",
+			types: []any{Message{}},
+			vars: map[string]any{
+				"msg": Message{
+					Next: &Message{},
+				},
+			},
+			want: types.True,
+		},
+		{
+			src:   "has(msg.next.next)",
+			types: []any{Message{}},
+			vars: map[string]any{
+				"msg": Message{},
+			},
+			want: types.False,
+		},
+		{
+			src:   "msg.?next.?message.orValue('none')",
+			types: []any{Message{}},
+			vars: map[string]any{
+				"msg": Message{},
+			},
+			want: types.String("none"),
+		},
+		{
+			src:   "msg.?next.?message.orValue('none')",
+			types: []any{Message{}},
+			vars: map[string]any{
+				"msg": Message{
+					Next: &Message{Message: "world"},
+				},
+			},
+			want: types.String("world"),
+		},
+		{
+			src:   "has(c.count)",
+			types: []any{Counter{}},
+			vars: map[string]any{
+				"c": Counter{},
+			},
+			want: types.False,
+		},
+		{
+			src:   "has(c.count)",
+			opts:  []easycel.RegistryOption{easycel.WithFieldPresence(easycel.FieldPresenceAlwaysSet)},
+			types: []any{Counter{}},
+			vars: map[string]any{
+				"c": Counter{},
+			},
+			want: types.True,
+		},
+		{
+			src:   "has(c.count) && !has(c.limit)",
+			opts:  []easycel.RegistryOption{easycel.WithFieldPresence(easycel.FieldPresenceOmitEmpty)},
+			types: []any{Counter{}},
+			vars: map[string]any{
+				"c": Counter{},
+			},
+			want: types.True,
+		},
+		{
+			src:   "c.?limit.orValue(10)",
+			opts:  []easycel.RegistryOption{easycel.WithFieldPresence(easycel.FieldPresenceOmitEmpty)},
+			types: []any{Counter{}},
+			vars: map[string]any{
+				"c": Counter{},
+			},
+			want: types.Int(10),
+		},
+		{
+			src:   "d.hash == b'\\x01\\x02\\x03\\x04' && size(d.hash) == 4",
+			types: []any{Digest{}},
+			vars: map[string]any{
+				"d": Digest{
+					Hash: [4]byte{1, 2, 3, 4},
+				},
+			},
+			want: types.True,
+		},
+		{
+			src:   "easycel_test.Digest{ hash: b'abcd', ids: [1, 2] }",
+			types: []any{Digest{}},
+			want: Digest{
+				Hash: [4]byte{'a', 'b', 'c', 'd'},
+				IDs:  [2]int{1, 2},
+			},
+		},
+		{
+			src: "sum([1, 2])",
+			funcs: map[string][]any{
+				"sum": {
+					func(ids [2]int) int {
+						return ids[0] + ids[1]
+					},
+				},
+			},
+			want: types.Int(3),
+		},
 		{
 			src:   "msg.list[0].message",
 			types: []any{Message{}},
@@ -608,6 +733,97 @@ func TestAll(t *testing.T) {
 		//	types: []any{Message{}},
 		//	want:  types.String("hello"),
 		//},
+		{
+			src: `acme.v1.Message{ message: "hello" }.message`,
+			namedTypes: map[string]any{
+				"acme.v1.Message": Message{},
+			},
+			want: types.String("hello"),
+		},
+		{
+			src:  `Message{ message: "hello", meta: Meta{ name: "meta" } }.meta.name`,
+			opts: []easycel.RegistryOption{easycel.WithContainer("acme.v1")},
+			namedTypes: map[string]any{
+				"acme.v1.Message": Message{},
+				"acme.v1.Meta":    Meta{},
+			},
+			want: types.String("meta"),
+		},
+		{
+			src:  `msg.next.message`,
+			opts: []easycel.RegistryOption{easycel.WithContainer("acme.v1")},
+			namedTypes: map[string]any{
+				"acme.v1.Message": Message{},
+			},
+			vars: map[string]any{
+				"msg": Message{
+					Next: &Message{Message: "world"},
+				},
+			},
+			want: types.String("world"),
+		},
+		{
+			src: `drawing.shape.name`,
+			interfaces: map[any][]any{
+				(*Shape)(nil): {Circle{}, Square{}},
+			},
+			types: []any{Drawing{}},
+			vars: map[string]any{
+				"drawing": Drawing{
+					Shape: Circle{Name: "circle", Radius: 1},
+				},
+			},
+			want: types.String("circle"),
+		},
+		{
+			src: `drawing.shapes.map(s, s.name)`,
+			interfaces: map[any][]any{
+				(*Shape)(nil): {Circle{}, &Square{}},
+			},
+			types: []any{Drawing{}},
+			vars: map[string]any{
+				"drawing": Drawing{
+					Shapes: []Shape{
+						Circle{Name: "circle"},
+						&Square{Name: "square"},
+					},
+				},
+			},
+			want: []ref.Val{types.String("circle"), types.String("square")},
+		},
+		{
+			src: `drawing.shape.area()`,
+			interfaces: map[any][]any{
+				(*Shape)(nil): {Circle{}, Square{}},
+			},
+			types: []any{Drawing{}},
+			methods: map[string][]any{
+				"area": {
+					func(s Shape) float64 {
+						return s.Area()
+					},
+				},
+			},
+			vars: map[string]any{
+				"drawing": Drawing{
+					Shape: Square{Side: 2},
+				},
+			},
+			want: types.Double(4),
+		},
+		{
+			src: `type(drawing.shape) == easycel_test.Square`,
+			interfaces: map[any][]any{
+				(*Shape)(nil): {Circle{}, Square{}},
+			},
+			types: []any{Drawing{}},
+			vars: map[string]any{
+				"drawing": Drawing{
+					Shape: Square{Side: 2},
+				},
+			},
+			want: types.True,
+		},
 		{
 			src:   `{ "message": "hello" }`,
 			types: []any{Message{}},
@@ -628,7 +844,8 @@ func TestAll(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.src, func(t *testing.T) {
-			registry := easycel.NewRegistry("test", easycel.WithTagName("json"))
+			opts := append([]easycel.RegistryOption{easycel.WithTagName("json")}, tt.opts...)
+			registry := easycel.NewRegistry("test", opts...)
 
 			for _, typ := range tt.types {
 				err := registry.RegisterType(typ)
@@ -637,6 +854,20 @@ func TestAll(t *testing.T) {
 				}
 			}
 
+			for iface, impls := range tt.interfaces {
+				err := registry.RegisterInterface(iface, impls...)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for name, typ := range tt.namedTypes {
+				err := registry.RegisterTypeAs(name, typ)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
 			for name, funcs := range tt.funcs {
 				for _, fun := range funcs {
 					err := registry.RegisterFunction(name, fun)
@@ -698,13 +929,1991 @@ func TestAll(t *testing.T) {
 	}
 }
 
-type Message struct {
-	Meta    Meta       `json:"meta"`
-	Message string     `json:"message"`
-	Next    *Message   `json:"next"`
-	Time    Timestamp  `json:"time"`
-	List    []*Message `json:"list"`
-	_       int
+func TestDeclareVariable(t *testing.T) {
+	tests := []struct {
+		src     string
+		declare func(r *easycel.Registry) error
+		vars    map[string]any
+		want    ref.Val
+	}{
+		{
+			src: "msgs[0].message",
+			declare: func(r *easycel.Registry) error {
+				return easycel.DeclareVariableOf[[]Message](r, "msgs")
+			},
+			vars: map[string]any{
+				"msgs": []Message{{Message: "hello"}},
+			},
+			want: types.String("hello"),
+		},
+		{
+			src: "m['a'].message",
+			declare: func(r *easycel.Registry) error {
+				return easycel.DeclareVariableOf[map[string]*Message](r, "m")
+			},
+			vars: map[string]any{
+				"m": map[string]*Message{"a": {Message: "hello"}},
+			},
+			want: types.String("hello"),
+		},
+		{
+			src: "v.foo",
+			declare: func(r *easycel.Registry) error {
+				return easycel.DeclareVariableOf[any](r, "v")
+			},
+			vars: map[string]any{
+				"v": map[string]any{"foo": "bar"},
+			},
+			want: types.String("bar"),
+		},
+		{
+			src: "v + 1",
+			declare: func(r *easycel.Registry) error {
+				return r.DeclareVariableType("v", cel.DynType)
+			},
+			vars: map[string]any{
+				"v": 1,
+			},
+			want: types.Int(2),
+		},
+		{
+			src: "s.name",
+			declare: func(r *easycel.Registry) error {
+				err := r.RegisterInterface((*Shape)(nil), Circle{}, Square{})
+				if err != nil {
+					return err
+				}
+				return r.DeclareVariable("s", reflect.TypeOf((*Shape)(nil)).Elem())
+			},
+			vars: map[string]any{
+				"s": Square{Name: "square"},
+			},
+			want: types.String("square"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			registry := easycel.NewRegistry("test", easycel.WithTagName("json"))
+			err := registry.RegisterType(Message{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = tt.declare(registry)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			env, err := easycel.NewEnvironment(cel.Lib(registry))
+			if err != nil {
+				t.Fatal(err)
+			}
+			program, err := env.Program(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, _, err := program.Eval(tt.vars)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Equal(tt.want) != types.True {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterTypeAsUsedType(t *testing.T) {
+	tests := []struct {
+		name    string
+		declare func(registry *easycel.Registry) error
+		want    string
+	}{
+		{
+			name: "variable",
+			declare: func(registry *easycel.Registry) error {
+				return registry.RegisterVariable("msg", Meta{})
+			},
+			want: "type easycel_test.Meta can't be registered as acme.Meta: type easycel_test.Meta is used by variable msg",
+		},
+		{
+			name: "function",
+			declare: func(registry *easycel.Registry) error {
+				return registry.RegisterFunction("name", func(m Meta) string {
+					return m.Name
+				})
+			},
+			want: "type easycel_test.Meta can't be registered as acme.Meta: type easycel_test.Meta is used by overload name|@|easycel_test.Meta|string",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := easycel.NewRegistry("test", easycel.WithTagName("json"))
+			err := tt.declare(registry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = registry.RegisterTypeAs("acme.Meta", Meta{})
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("got error %v, want %s", err, tt.want)
+			}
+		})
+	}
+
+	registry := easycel.NewRegistry("test", easycel.WithTagName("json"))
+	err := registry.RegisterTypeAs("acme.Meta", Meta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterVariable("msg", Meta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := easycel.NewEnvironment(cel.Lib(registry))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = env.Program("msg.name")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+type Request struct {
+	User    string   `json:"user"`
+	Groups  []string `json:"groups"`
+	Message *Message `json:"message"`
+	Secret  string   `json:"-"`
+}
+
+func TestRegisterInput(t *testing.T) {
+	registry := easycel.NewRegistry("test", easycel.WithTagName("json"))
+	err := registry.RegisterType(Message{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterInput(Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := easycel.NewEnvironment(cel.Lib(registry))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = env.Program("Secret == ''")
+	if err == nil {
+		t.Fatal("expected undeclared reference to Secret")
+	}
+
+	program, err := env.Program("user == 'alice' && 'admin' in groups && message.message == 'hello'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	activation, err := registry.NewActivation(&Request{
+		User:    "alice",
+		Groups:  []string{"admin"},
+		Message: &Message{Message: "hello"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _, err := program.Eval(activation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != types.True {
+		t.Errorf("got %#v, want true", got)
+	}
+
+	_, err = registry.NewActivation(Message{})
+	if err == nil {
+		t.Error("expected error for input of the wrong type")
+	}
+}
+
+func TestLazyVariable(t *testing.T) {
+	registry := easycel.NewRegistry("test", easycel.WithTagName("json"))
+	calls := map[string]int{}
+	err := registry.RegisterLazyVariable("groups", func(ctx context.Context) ([]string, error) {
+		calls["groups"]++
+		return []string{"admin"}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterLazyVariable("cert", func(ctx context.Context) (string, error) {
+		calls["cert"]++
+		return "", fmt.Errorf("expired")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterVariable("user", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := easycel.NewEnvironment(cel.Lib(registry))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ast, err := env.Compile("user == 'alice' && 'admin' in groups && size(groups) == 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := registry.LazyVariables(ast); !reflect.DeepEqual(got, []string{"groups"}) {
+		t.Errorf("got lazy variables %v, want [groups]", got)
+	}
+
+	program, err := env.Program("user == 'alice' && 'admin' in groups && size(groups) == 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	activation, err := registry.NewLazyActivation(context.Background(), map[string]any{"user": "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _, err := program.Eval(activation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != types.True {
+		t.Errorf("got %#v, want true", got)
+	}
+	if !reflect.DeepEqual(calls, map[string]int{"groups": 1}) {
+		t.Errorf("got calls %v, want groups resolved once", calls)
+	}
+
+	program, err = env.Program("cert == ''")
+	if err != nil {
+		t.Fatal(err)
+	}
+	activation, err = registry.NewLazyActivation(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = program.Eval(activation)
+	if err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("got error %v, want resolver error", err)
+	}
+}
+
+func TestCost(t *testing.T) {
+	registry := easycel.NewRegistry("test", easycel.WithCostLimit(250))
+	err := registry.RegisterFunction("expensive", func(s string) string {
+		return s
+	}, easycel.WithCost(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterFunction("scan", func(s string) bool {
+		return true
+	}, easycel.WithSizeCost(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := easycel.NewEnvironment(cel.Lib(registry))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	est, err := env.EstimateCost("expensive('a')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if est.Min != 100 || est.Max != 100 {
+		t.Errorf("got estimate %+v, want 100", est)
+	}
+
+	est, err = env.EstimateCost("scan('abcd')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if est.Min != 40 || est.Max != 40 {
+		t.Errorf("got estimate %+v, want 40", est)
+	}
+
+	program, err := env.Program("expensive('a') + expensive('b')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, details, err := program.Eval(map[string]any{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cost := details.ActualCost(); cost == nil || *cost < 200 {
+		t.Errorf("got actual cost %v, want at least 200", cost)
+	}
+
+	program, err = env.Program("expensive('a') + expensive('b') + expensive('c')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = program.Eval(map[string]any{})
+	if err == nil || !strings.Contains(err.Error(), "cost limit exceeded") {
+		t.Errorf("got error %v, want cost limit exceeded", err)
+	}
+}
+
+func TestPartialEval(t *testing.T) {
+	registry := easycel.NewRegistry("test", easycel.WithTagName("json"))
+	err := registry.RegisterType(Message{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterType(Meta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterVariable("user", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterVariable("msg", Message{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := easycel.NewEnvironment(cel.Lib(registry))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		src          string
+		vars         map[string]any
+		unknowns     []string
+		want         ref.Val
+		wantResidual string
+	}{
+		{
+			src:      "user == 'alice' && msg.message == 'hello'",
+			vars:     map[string]any{"user": "bob"},
+			unknowns: []string{"msg"},
+			want:     types.False,
+		},
+		{
+			src:          "user == 'alice' && msg.message == 'hello'",
+			vars:         map[string]any{"user": "alice"},
+			unknowns:     []string{"msg"},
+			wantResidual: `msg.message == "hello"`,
+		},
+		{
+			src: "msg.message == 'hello' && msg.meta.name == 'meta'",
+			vars: map[string]any{
+				"msg": Message{Message: "hello"},
+			},
+			unknowns:     []string{"msg.meta.name"},
+			wantResidual: `msg.meta.name == "meta"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			got, err := env.PartialEval(tt.src, tt.vars, tt.unknowns...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want != nil {
+				if !got.Complete() || got.Value.Equal(tt.want) != types.True {
+					t.Errorf("got %#v, want %#v", got, tt.want)
+				}
+				return
+			}
+			if got.Complete() {
+				t.Fatalf("got complete result %#v, want residual", got.Value)
+			}
+			if got.ResidualSource != tt.wantResidual {
+				t.Errorf("got residual %q, want %q", got.ResidualSource, tt.wantResidual)
+			}
+
+			program, err := env.Program(got.ResidualSource)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, _, err := program.Eval(map[string]any{
+				"user": "alice",
+				"msg": Message{
+					Message: "hello",
+					Meta:    Meta{Name: "meta"},
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out != types.True {
+				t.Errorf("got residual result %#v, want true", out)
+			}
+		})
+	}
+}
+
+func TestExplain(t *testing.T) {
+	registry := easycel.NewRegistry("test", easycel.WithTagName("json"))
+	err := registry.RegisterVariable("user", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterFunction("greet", func(s string) string {
+		return "hello " + s
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := easycel.NewEnvironment(cel.Lib(registry))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	explanation, err := env.Explain("user == 'alice' || greet(user) == 'hello alice'", map[string]any{"user": "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if explanation.Value != types.False {
+		t.Errorf("got %#v, want false", explanation.Value)
+	}
+
+	want := `user == "alice" || greet(user) == "hello alice" => false
+├── user == "alice" => false
+│   ├── user => "bob"
+│   └── "alice" => "alice"
+└── greet(user) == "hello alice" => false
+    ├── greet(user) => "hello bob"
+    │   └── user => "bob"
+    └── "hello alice" => "hello alice"
+`
+	if got := explanation.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	data, err := explanation.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var root struct {
+		Root easycel.ExplainNode `json:"root"`
+	}
+	err = json.Unmarshal(data, &root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := root.Root.Children[1].Children[0]
+	if call.Function != "greet" || call.Overload != "greet|@|string|string" || call.Value != `"hello bob"` {
+		t.Errorf("got call %+v", call)
+	}
+
+	explanation, err = env.Explain("greet(user) == 'hello' && user == 'alice'", map[string]any{"user": "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if skipped := explanation.Root.Children[1]; skipped.Evaluated {
+		t.Errorf("got %+v, want short-circuited clause to be not evaluated", skipped)
+	}
+
+	explanation, err = env.Explain("[1, 2].exists(i, i > 2)", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `[1, 2].exists(i, i > 2) => false
+├── [1, 2] => [1 2]
+│   ├── 1 => 1
+│   └── 2 => 2
+├── @not_strictly_false(!@result) => true
+│   └── !@result => true
+│       └── @result => false
+├── @result || i > 2 => false
+│   ├── @result => false
+│   └── i > 2 => false
+│       ├── i => 2
+│       └── 2 => 2
+└── @result => false
+`
+	if got := explanation.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	err = registry.RegisterFunction("greet", func(n int64) string {
+		return fmt.Sprintf("hello #%d", n)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.DeclareVariableType("any", cel.DynType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err = easycel.NewEnvironment(cel.Lib(registry))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		value    any
+		overload string
+	}{
+		{value: "bob", overload: "greet|@|string|string"},
+		{value: 1, overload: "greet|@|int|string"},
+	} {
+		explanation, err = env.Explain("greet(any)", map[string]any{"any": tt.value})
+		if err != nil {
+			t.Fatal(err)
+		}
+		call := explanation.Root
+		if call.Overload != tt.overload || len(call.Candidates) != 2 {
+			t.Errorf("got call %+v, want overload %s of 2 candidates", call, tt.overload)
+		}
+	}
+}
+
+func TestFieldPresenceConvertToMap(t *testing.T) {
+	tests := []struct {
+		presence easycel.FieldPresence
+		want     map[string]any
+	}{
+		{
+			presence: easycel.FieldPresenceZeroUnset,
+			want:     map[string]any{},
+		},
+		{
+			presence: easycel.FieldPresenceAlwaysSet,
+			want:     map[string]any{"count": int64(0), "limit": int64(0)},
+		},
+		{
+			presence: easycel.FieldPresenceOmitEmpty,
+			want:     map[string]any{"count": int64(0)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.presence), func(t *testing.T) {
+			registry := easycel.NewRegistry("test", easycel.WithTagName("json"), easycel.WithFieldPresence(tt.presence))
+			err := registry.RegisterType(Counter{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := registry.NativeToValue(Counter{}).ConvertToNative(reflect.TypeOf(map[string]any{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArrayLength(t *testing.T) {
+	registry := easycel.NewRegistry("test", easycel.WithTagName("json"))
+	err := registry.RegisterType(Digest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := easycel.NewEnvironment(cel.Lib(registry))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, src := range []string{
+		"easycel_test.Digest{ ids: [1] }",
+		"easycel_test.Digest{ hash: b'abc' }",
+	} {
+		program, err := env.Program(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, err = program.Eval(map[string]any{})
+		if err == nil {
+			t.Errorf("%s: expected length error", src)
+		}
+	}
+
+	list := registry.NativeToValue([]int{1, 2, 3})
+	_, err = list.ConvertToNative(reflect.TypeOf([2]int{}))
+	if err == nil {
+		t.Errorf("expected length error converting a list to an array")
+	}
+	got, err := list.ConvertToNative(reflect.TypeOf([3]int{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != [3]int{1, 2, 3} {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestEvalError(t *testing.T) {
+	errInvalid := errors.New("invalid value")
+	registry := easycel.NewRegistry("test")
+	err := registry.RegisterFunction("check", func(s string) (bool, error) {
+		if s == "bad" {
+			return false, errInvalid
+		}
+		return true, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := easycel.NewEnvironment(cel.Lib(registry))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	program, err := env.Program("check('good') &&\n  check('bad')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = program.Eval(map[string]any{})
+	if !errors.Is(err, errInvalid) {
+		t.Fatalf("got error %v, want %v", err, errInvalid)
+	}
+	var evalErr *easycel.EvalError
+	if !errors.As(err, &evalErr) {
+		t.Fatalf("got error %T, want *easycel.EvalError", err)
+	}
+	if evalErr.Line != 2 || evalErr.Column != 7 {
+		t.Errorf("got position %d:%d, want 2:7", evalErr.Line, evalErr.Column)
+	}
+	if evalErr.Snippet != "  check('bad')" {
+		t.Errorf("got snippet %q", evalErr.Snippet)
+	}
+	if evalErr.Function != "check" || evalErr.Overload != "check|@|string|bool" {
+		t.Errorf("got function %q overload %q", evalErr.Function, evalErr.Overload)
+	}
+	t.Log(err)
+
+	// Errors in the body of a macro are positioned at the failed call rather than the macro.
+	program, err = env.Program("['good', 'bad'].map(s, check(s))")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = program.Eval(map[string]any{})
+	if !errors.As(err, &evalErr) {
+		t.Fatalf("got error %T, want *easycel.EvalError", err)
+	}
+	if evalErr.Line != 1 || evalErr.Column != 28 {
+		t.Errorf("got position %d:%d, want 1:28", evalErr.Line, evalErr.Column)
+	}
+	t.Log(err)
+}
+
+func TestDiagnostics(t *testing.T) {
+	registry := easycel.NewRegistry("test", easycel.WithTagName("json"))
+	err := registry.RegisterType(Message{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterType(Meta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterVariable("msg", Message{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterVariable("msgs", []Message{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterFunction("greet", func(s string) string {
+		return "hello " + s
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := easycel.NewEnvironment(cel.Lib(registry))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		src        string
+		line       int
+		column     int
+		offset     int
+		suggestion string
+	}{
+		{
+			src:        "msg.meta.name == 'x' &&\n msg.mesage == 'x'",
+			line:       2,
+			column:     4,
+			offset:     28,
+			suggestion: "message",
+		},
+		{
+			src:        "easycel_test.Message{ mesage: 'x' }",
+			line:       1,
+			column:     28,
+			offset:     28,
+			suggestion: "message",
+		},
+		{
+			src:        "gret('x')",
+			line:       1,
+			column:     4,
+			offset:     4,
+			suggestion: "greet",
+		},
+		{
+			src:        "mgs.message",
+			line:       1,
+			column:     0,
+			offset:     0,
+			suggestion: "msg",
+		},
+		{
+			src:        "msgs.exists(m, m.mesage == 'x')",
+			line:       1,
+			column:     16,
+			offset:     16,
+			suggestion: "message",
+		},
+		{
+			src:        "easycel_test.Mesage{ message: 'x' }",
+			line:       1,
+			column:     19,
+			offset:     19,
+			suggestion: "easycel_test.Message",
+		},
+		{
+			src:    "msg.unrelated",
+			line:   1,
+			column: 3,
+			offset: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := env.Compile(tt.src)
+			var diags *easycel.Diagnostics
+			if !errors.As(err, &diags) {
+				t.Fatalf("got error %v, want *easycel.Diagnostics", err)
+			}
+			if len(diags.Items) != 1 {
+				t.Fatalf("got %d diagnostics, want 1: %v", len(diags.Items), err)
+			}
+			diag := diags.Items[0]
+			if diag.Severity != easycel.SeverityError {
+				t.Errorf("got severity %q", diag.Severity)
+			}
+			if diag.Line != tt.line || diag.Column != tt.column || diag.Offset != tt.offset {
+				t.Errorf("got position %d:%d@%d, want %d:%d@%d", diag.Line, diag.Column, diag.Offset, tt.line, tt.column, tt.offset)
+			}
+			if diag.Suggestion != tt.suggestion {
+				t.Errorf("got suggestion %q, want %q", diag.Suggestion, tt.suggestion)
+			}
+			t.Log(err)
+		})
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	registry := easycel.NewRegistry("test", easycel.WithTagName("json"))
+	err := registry.RegisterType(Message{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterType(Meta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterVariable("msg", Message{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterVariable("prefix", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	greet := func(s string) string {
+		return "hello " + s
+	}
+	err = registry.RegisterFunction("greet", greet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := easycel.NewEnvironment(cel.Lib(registry))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ast, err := env.Compile("msg.meta.name == prefix && greet(msg.message) != '' && has(msg.next) && msg.?next.?message.hasValue()")
+	if err != nil {
+		t.Fatal(err)
+	}
+	analysis, err := registry.Analyze(ast)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	variables := []string{}
+	for _, v := range analysis.Variables {
+		variables = append(variables, fmt.Sprintf("%s %s %v", v.Name, v.Type, v.GoType))
+	}
+	wantVariables := []string{
+		"msg easycel_test.Message easycel_test.Message",
+		"prefix string string",
+	}
+	if !reflect.DeepEqual(variables, wantVariables) {
+		t.Errorf("got variables %q, want %q", variables, wantVariables)
+	}
+
+	fields := []string{}
+	for _, f := range analysis.Fields {
+		fields = append(fields, fmt.Sprintf("%s %s.%s %s %v", f.Path, f.Struct, f.Field, f.Type, f.GoType))
+	}
+	wantFields := []string{
+		"msg.?next.?message easycel_test.Message.message string string",
+		"msg.message easycel_test.Message.message string string",
+		"msg.meta.name easycel_test.Meta.name string string",
+		"msg.next easycel_test.Message.next wrapper(easycel_test.Message) *easycel_test.Message",
+	}
+	if !reflect.DeepEqual(fields, wantFields) {
+		t.Errorf("got fields %q, want %q", fields, wantFields)
+	}
+
+	if len(analysis.Functions) != 1 || analysis.Functions[0].Name != "greet" || len(analysis.Functions[0].Overloads) != 1 {
+		t.Fatalf("got functions %+v, want greet", analysis.Functions)
+	}
+	overload := analysis.Functions[0].Overloads[0]
+	if overload.ID != "greet|@|string|string" || overload.GoType != reflect.TypeOf(greet) {
+		t.Errorf("got overload %+v", overload)
+	}
+
+	err = registry.RegisterVariable("msgs", []Message{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err = easycel.NewEnvironment(cel.Lib(registry))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ast, err = env.Compile("msgs.exists(m, m.meta.name == 'x') && [msg].all(msg, msg.message != '')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	analysis, err = registry.Analyze(ast)
+	if err != nil {
+		t.Fatal(err)
+	}
+	variables = []string{}
+	for _, v := range analysis.Variables {
+		variables = append(variables, v.Name)
+	}
+	wantVariables = []string{"msg", "msgs"}
+	if !reflect.DeepEqual(variables, wantVariables) || len(analysis.Fields) != 0 {
+		t.Errorf("got variables %q and fields %+v, want %q without fields", variables, analysis.Fields, wantVariables)
+	}
+}
+
+func TestExpectType(t *testing.T) {
+	registry := easycel.NewRegistry("test", easycel.WithTagName("json"))
+	err := registry.RegisterType(Message{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterVariable("msg", Message{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.DeclareVariableType("any", cel.DynType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := easycel.NewEnvironment(cel.Lib(registry))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]any{
+		"msg": Message{Message: "hello"},
+		"any": "world",
+	}
+
+	tests := []struct {
+		src     string
+		opt     easycel.CompileOption
+		wantErr bool
+	}{
+		{src: "msg.message == 'hello'", opt: easycel.ExpectTypeOf[bool]()},
+		{src: "msg.message", opt: easycel.ExpectTypeOf[bool](), wantErr: true},
+		{src: "msg.message", opt: easycel.ExpectType(reflect.TypeOf(""))},
+		{src: "any", opt: easycel.ExpectTypeOf[string]()},
+		{src: "msg", opt: easycel.ExpectTypeOf[Message]()},
+		{src: "msg.next", opt: easycel.ExpectTypeOf[*Message]()},
+		{src: "[msg.message]", opt: easycel.ExpectTypeOf[[]string]()},
+		{src: "[msg.message]", opt: easycel.ExpectTypeOf[map[string]string](), wantErr: true},
+	}
+	for _, tt := range tests {
+		_, err := env.Compile(tt.src, tt.opt)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: got error %v, want error %v", tt.src, err, tt.wantErr)
+		}
+	}
+
+	_, err = env.Compile("1 +\n  msg.message.size()", easycel.ExpectTypeOf[string]())
+	var diags *easycel.Diagnostics
+	if !errors.As(err, &diags) {
+		t.Fatalf("expected diagnostics, got %v", err)
+	}
+	if got := diags.Items[0]; got.Message != "expression of type int, expected string" || got.Line != 1 || got.Column != 0 {
+		t.Errorf("got diagnostic %+v", got)
+	}
+
+	ast, err := env.Compile("msg.message == 'hello'", easycel.ExpectTypeOf[bool]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	program, err := env.ProgramFromAst(ast)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, err := easycel.EvalBool(program, vars)
+	if err != nil || !ok {
+		t.Errorf("got %v, %v, want true", ok, err)
+	}
+
+	program, err = env.Program("msg.message + ' ' + any")
+	if err != nil {
+		t.Fatal(err)
+	}
+	str, err := easycel.EvalString(program, vars)
+	if err != nil || str != "hello world" {
+		t.Errorf("got %q, %v, want hello world", str, err)
+	}
+	_, err = easycel.EvalBool(program, vars)
+	if err == nil {
+		t.Errorf("expected conversion error")
+	}
+
+	program, err = env.Program("msg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := easycel.EvalAs[Message](program, vars)
+	if err != nil || msg.Message != "hello" {
+		t.Errorf("got %+v, %v", msg, err)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	registry := easycel.NewRegistry("test", easycel.WithTagName("json"))
+	for _, typ := range []any{Message{}, Meta{}} {
+		err := registry.RegisterType(typ)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, conversion := range []any{
+		func(ts Timestamp) types.Timestamp {
+			return types.Timestamp{Time: ts.Time}
+		},
+		func(ts types.Timestamp) Timestamp {
+			return Timestamp{Time: ts.Time}
+		},
+	} {
+		err := registry.RegisterConversion(conversion)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := registry.RegisterVariable("msgs", []*Message{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := easycel.NewEnvironment(cel.Lib(registry))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0).UTC()
+	vars := map[string]any{
+		"msgs": []*Message{
+			{Message: "a", Meta: Meta{Name: "x"}, Time: Timestamp{now}},
+			{Message: "b"},
+		},
+	}
+	eval := func(src string) ref.Val {
+		t.Helper()
+		program, err := env.Program(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		val, _, err := program.Eval(vars)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return val
+	}
+
+	program, err := env.Program("msgs[0].time")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts, err := easycel.EvalAs[Timestamp](program, vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ts.Equal(now) {
+		t.Errorf("got %v, want the reverse conversion of the registry", ts)
+	}
+
+	var list []Message
+	err = registry.Decode(eval("msgs.filter(m, m.message == 'a')"), &list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Meta.Name != "x" || !list[0].Time.Equal(now) {
+		t.Errorf("got %+v", list)
+	}
+
+	var byName map[string]*Message
+	err = easycel.Decode(eval("{'b': msgs[1]}"), &byName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if byName["b"] == nil || byName["b"].Message != "b" {
+		t.Errorf("got %+v", byName)
+	}
+
+	var fromMap Message
+	err = registry.Decode(eval("{'message': 'hi', 'meta': {'name': 'y'}, 'list': [{'message': 'z'}]}"), &fromMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fromMap.Message != "hi" || fromMap.Meta.Name != "y" || len(fromMap.List) != 1 || fromMap.List[0].Message != "z" {
+		t.Errorf("got %+v", fromMap)
+	}
+
+	type View struct {
+		Text string    `json:"message"`
+		Time Timestamp `json:"time"`
+	}
+	var view View
+	err = registry.Decode(eval("msgs[0]"), &view)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if view.Text != "a" || !view.Time.Equal(now) {
+		t.Errorf("got %+v", view)
+	}
+
+	var d time.Duration
+	err = easycel.Decode(eval("duration('1s')"), &d)
+	if err != nil || d != time.Second {
+		t.Errorf("got %v, %v", d, err)
+	}
+
+	var anything any
+	err = easycel.Decode(eval("[1, 'a', {'k': true}]"), &anything)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []any{int64(1), "a", map[string]any{"k": true}}; !reflect.DeepEqual(anything, want) {
+		t.Errorf("got %#v, want %#v", anything, want)
+	}
+
+	err = registry.Decode(eval("[{'meta': {'name': 'x'}}, {'meta': {'name': 1}}]"), &list)
+	if err == nil || err.Error() != "[1].meta.name: expected string, got int" {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	newRegistry := func(reverse bool, extra bool) *easycel.Registry {
+		registry := easycel.NewRegistry("test", easycel.WithTagName("json"))
+		steps := []func() error{
+			func() error { return registry.RegisterType(Message{}) },
+			func() error { return registry.RegisterType(Meta{}) },
+			func() error { return registry.RegisterVariable("msg", Message{}) },
+			func() error { return registry.RegisterVariable("name", "") },
+			func() error {
+				return registry.RegisterFunction("greet", func(s string) string { return s })
+			},
+			func() error {
+				return registry.RegisterFunction("greet", func(s int) string { return "" })
+			},
+			func() error {
+				return registry.RegisterMethod("size", func(m Message) int { return 0 }, easycel.WithCost(10))
+			},
+		}
+		if extra {
+			steps = append(steps, func() error { return registry.RegisterVariable("extra", 0) })
+		}
+		if reverse {
+			for i, j := 0, len(steps)-1; i < j; i, j = i+1, j-1 {
+				steps[i], steps[j] = steps[j], steps[i]
+			}
+		}
+		for _, step := range steps {
+			err := step()
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		return registry
+	}
+
+	fingerprint := func(r *easycel.Registry) string {
+		t.Helper()
+		f, err := r.Fingerprint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+
+	base := fingerprint(newRegistry(false, false))
+	for i := 0; i < 10; i++ {
+		if got := fingerprint(newRegistry(i%2 == 1, false)); got != base {
+			t.Fatalf("got fingerprint %s, want %s", got, base)
+		}
+	}
+	if got := fingerprint(newRegistry(false, true)); got == base {
+		t.Errorf("fingerprint did not change with an extra variable")
+	}
+
+	replaced := newRegistry(false, false)
+	err := replaced.ReplaceFunction("greet", func(s string) string { return "hello " + s })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fingerprint(replaced); got == base {
+		t.Errorf("fingerprint did not change with a replaced function")
+	}
+
+	withType := newRegistry(false, false)
+	err = withType.RegisterType(types.NewOpaqueType("acme.Handle"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fingerprint(withType); got == base {
+		t.Errorf("fingerprint did not change with a CEL type")
+	}
+
+	if fingerprint(easycel.NewRegistry("test", easycel.WithTagName("json"))) == fingerprint(easycel.NewRegistry("test", easycel.WithTagName("yaml"))) {
+		t.Errorf("fingerprint did not change with the tag name")
+	}
+}
+
+func TestIntrospection(t *testing.T) {
+	registry := easycel.NewRegistry("test", easycel.WithTagName("json"))
+	err := registry.RegisterType(Meta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterInterface((*Shape)(nil), Circle{}, Square{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterVariable("meta", Meta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.DeclareVariableType("any", cel.DynType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	greet := func(s string, n int) string { return s }
+	err = registry.RegisterMethod("greet", greet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterConversion(func(ts Timestamp) types.Timestamp {
+		return types.Timestamp{Time: ts.Time}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	typeNames := []string{}
+	for _, typ := range registry.Types() {
+		fields := []string{}
+		for _, field := range typ.Fields {
+			fields = append(fields, fmt.Sprintf("%s %s %v", field.Name, field.Type, field.GoType))
+		}
+		typeNames = append(typeNames, fmt.Sprintf("%s %v %v %q", typ.Name, typ.GoType, typ.Implementations, fields))
+	}
+	wantTypes := []string{
+		`easycel_test.Circle easycel_test.Circle [] ["name string string" "radius double float64"]`,
+		`easycel_test.Meta easycel_test.Meta [] ["name string string"]`,
+		`easycel_test.Shape easycel_test.Shape [easycel_test.Circle easycel_test.Square] ["name string <nil>"]`,
+		`easycel_test.Square easycel_test.Square [] ["name string string" "side double float64"]`,
+	}
+	if !reflect.DeepEqual(typeNames, wantTypes) {
+		t.Errorf("got types %q, want %q", typeNames, wantTypes)
+	}
+
+	variables := []string{}
+	for _, v := range registry.Variables() {
+		variables = append(variables, fmt.Sprintf("%s %s %v", v.Name, v.Type, v.GoType))
+	}
+	wantVariables := []string{
+		"any dyn <nil>",
+		"meta easycel_test.Meta easycel_test.Meta",
+	}
+	if !reflect.DeepEqual(variables, wantVariables) {
+		t.Errorf("got variables %q, want %q", variables, wantVariables)
+	}
+
+	functions, err := registry.Functions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(functions) != 1 || functions[0].Name != "greet" || len(functions[0].Overloads) != 1 {
+		t.Fatalf("got functions %+v", functions)
+	}
+	overload := functions[0].Overloads[0]
+	if overload.ID != "greet|member@|string,int|string" || overload.GoType != reflect.TypeOf(greet) {
+		t.Errorf("got overload %+v", overload)
+	}
+	if got := overload.Signature(); got != "string.greet(int) -> string" {
+		t.Errorf("got signature %q", got)
+	}
+
+	conversions := registry.Conversions()
+	if len(conversions) != 1 || conversions[0].From != reflect.TypeOf(Timestamp{}) || conversions[0].To != reflect.TypeOf(types.Timestamp{}) {
+		t.Errorf("got conversions %+v", conversions)
+	}
+}
+
+type Account struct {
+	Name   string   `json:"name" doc:"The display name."`
+	Emails []string `json:"emails" doc:"Verified email addresses."`
+}
+
+type AccountInput struct {
+	Account Account `json:"account" doc:"The account | making the request."`
+	Now     int64   `json:"now"`
+}
+
+func TestDocumentation(t *testing.T) {
+	registry := easycel.NewRegistry("accounts", easycel.WithTagName("json"))
+	err := registry.RegisterType(Account{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterInput(AccountInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterMethod("domain", func(a Account) string { return "" },
+		easycel.WithDescription("Returns the domain of the first email."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterFunction("now", func() int64 { return 0 })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := registry.Documentation()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# accounts
+
+## Functions
+
+### domain
+
+- ` + "`easycel_test.Account.domain() -> string`" + `: Returns the domain of the first email.
+
+### now
+
+- ` + "`now() -> int`" + `
+
+## Variables
+
+| Name | Type | Description |
+| --- | --- | --- |
+| ` + "`account` | `easycel_test.Account`" + ` | The account \| making the request. |
+| ` + "`now` | `int`" + ` |  |
+
+## Types
+
+### easycel_test.Account
+
+| Field | Type | Description |
+| --- | --- | --- |
+| ` + "`name` | `string`" + ` | The display name. |
+| ` + "`emails` | `list(string)`" + ` | Verified email addresses. |
+`
+	if got := doc.Markdown(); got != want {
+		t.Errorf("got markdown\n%s\nwant\n%s", got, want)
+	}
+
+	data, err := doc.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded easycel.Documentation
+	err = json.Unmarshal(data, &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&decoded, doc) {
+		t.Errorf("got %s", data)
+	}
+}
+
+func TestFunctionOptions(t *testing.T) {
+	registry := easycel.NewRegistry("test")
+	err := registry.RegisterMethod("repeat", func(s string, n int) string {
+		return strings.Repeat(s, n)
+	},
+		easycel.WithDescription("Repeats the string."),
+		easycel.WithParamNames("count"),
+		easycel.WithExamples("'ab'.repeat(2) == 'abab'"),
+		easycel.WithDeprecated("use strings.repeat instead"),
+		easycel.WithCost(5),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterFunction("pair", func(a, b string) string {
+		return a + b
+	}, easycel.WithParamNames("a"))
+	if err == nil {
+		t.Errorf("expected error for missing param names")
+	}
+
+	env, err := cel.NewEnv(cel.Lib(registry))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn := env.Functions()["repeat"]
+	if got, want := fn.Description(), "Repeats the string.\nDeprecated: use strings.repeat instead"; got != want {
+		t.Errorf("got description %q, want %q", got, want)
+	}
+	examples := fn.OverloadDecls()[0].Examples()
+	if !reflect.DeepEqual(examples, []string{"'ab'.repeat(2) == 'abab'"}) {
+		t.Errorf("got examples %q", examples)
+	}
+
+	doc, err := registry.Documentation()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "### repeat\n\n" +
+		"- `string.repeat(count int) -> string`: Repeats the string.\n" +
+		"  - **Deprecated:** use strings.repeat instead\n" +
+		"  - Example: `'ab'.repeat(2) == 'abab'`\n"
+	if got := doc.Markdown(); !strings.Contains(got, want) {
+		t.Errorf("got markdown\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCompose(t *testing.T) {
+	base := easycel.NewRegistry("base", easycel.WithTagName("json"))
+	err := base.RegisterFunction("greet", func(name string) string {
+		return "hello " + name
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = base.RegisterVariable("region", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clone, err := base.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = clone.RegisterVariable("extra", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(base.Variables()) != 1 {
+		t.Errorf("registration on the clone is seen by the registry")
+	}
+	err = clone.RegisterVariable("region", "")
+	if err == nil {
+		t.Errorf("expected error for variable registered again on a clone")
+	}
+
+	circles, err := base.Extend("circles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = circles.RegisterType(Circle{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = circles.RegisterFunction("greet", func(name string) string {
+		return "hi " + name
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = circles.RegisterVariable("region", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := easycel.NewEnvironment(cel.Lib(circles))
+	if err != nil {
+		t.Fatal(err)
+	}
+	program, err := env.Program("greet(string(region))")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := easycel.EvalString(program, map[string]any{"region": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "hi 1" {
+		t.Errorf("got %q, want overridden function", got)
+	}
+
+	squares, err := base.Extend("squares")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = squares.RegisterType(Square{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	circles2, err := base.Extend("circles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged, err := easycel.Merge("shapes", circles2, squares)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = merged.RegisterType(Circle{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err = easycel.NewEnvironment(cel.Lib(merged))
+	if err != nil {
+		t.Fatal(err)
+	}
+	program, err = env.Program(`greet(region) + " " + easycel_test.Circle{name: "c"}.name + " " + easycel_test.Square{name: "s"}.name`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = easycel.EvalString(program, map[string]any{"region": "eu"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "hello eu c s" {
+		t.Errorf("got %q", got)
+	}
+
+	_, err = easycel.Merge("conflict", circles, squares)
+	if err == nil || !strings.Contains(err.Error(), "conflicts") {
+		t.Errorf("expected conflict error, got %v", err)
+	}
+
+	withPrefix := func(prefix string) *easycel.Registry {
+		registry := easycel.NewRegistry(prefix)
+		err := registry.RegisterFunction("prefixed", func(s string) string {
+			return prefix + s
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return registry
+	}
+	_, err = easycel.Merge("closures", withPrefix("a"), withPrefix("b"))
+	if err == nil || !strings.Contains(err.Error(), "overload prefixed|@|string|string of prefixed conflicts") {
+		t.Errorf("expected conflict error for closures, got %v", err)
+	}
+
+	renamed := easycel.NewRegistry("renamed", easycel.WithTagName("json"))
+	err = renamed.RegisterTypeAs("acme.Circle", Circle{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = renamed.RegisterFunction("radius", func(c Circle) float64 {
+		return c.Radius
+	}, easycel.WithOverloadID("circle_radius"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clone, err = renamed.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	functions, err := clone.Functions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o := functions[0].Overloads[0]; o.ID != "circle_radius" || o.ArgTypes[0].String() != "acme.Circle" {
+		t.Errorf("got overload %s of %v, want the cloned overload", o.ID, o.ArgTypes)
+	}
+}
+
+func TestUnregister(t *testing.T) {
+	registry := easycel.NewRegistry("test", easycel.WithTagName("json"))
+	greet := func(name string) string {
+		return "hello " + name
+	}
+	err := registry.RegisterFunction("greet", greet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterFunction("greet", greet)
+	if err == nil || !strings.Contains(err.Error(), "greet|@|string|string already registered by func(string) string") {
+		t.Errorf("expected duplicate overload error, got %v", err)
+	}
+	err = registry.ReplaceFunction("greet", func(name string) string {
+		return "hi " + name
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterFunction("greet", func(n int) string {
+		return "number"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.UnregisterOverload("greet|@|int|string")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = registry.RegisterType(Meta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterVariable("meta", Meta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.UnregisterType(Meta{})
+	if err == nil || !strings.Contains(err.Error(), "used by variable meta") {
+		t.Errorf("expected type in use error, got %v", err)
+	}
+	err = registry.ReplaceVariable("meta", nil)
+	if err == nil {
+		t.Errorf("expected error for nil variable value")
+	}
+	err = registry.ReplaceVariable("meta", make(chan int))
+	if err == nil {
+		t.Errorf("expected error for unsupported variable type")
+	}
+	if variables := registry.Variables(); len(variables) != 1 || variables[0].Type.String() != "easycel_test.Meta" {
+		t.Errorf("got variables %v, want the variable kept after a failed replace", variables)
+	}
+	err = registry.ReplaceVariable("meta", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.UnregisterType(Meta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterVariable("unused", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.UnregisterVariable("unused")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := easycel.NewEnvironment(cel.Lib(registry))
+	if err != nil {
+		t.Fatal(err)
+	}
+	program, err := env.Program("greet(meta)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := easycel.EvalString(program, map[string]any{"meta": "eu"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "hi eu" {
+		t.Errorf("got %q, want replaced function", got)
+	}
+	for _, src := range []string{"greet(1)", "unused", "easycel_test.Meta{}"} {
+		_, err = env.Compile(src)
+		if err == nil {
+			t.Errorf("expected %s to be unregistered", src)
+		}
+	}
+
+	err = registry.UnregisterFunction("greet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.UnregisterFunction("greet")
+	if err == nil {
+		t.Errorf("expected error for unregistered function")
+	}
+}
+
+func TestReloadableEnvironment(t *testing.T) {
+	registry := easycel.NewRegistry("test")
+	err := registry.RegisterFunction("greet", func(name string) string {
+		return "hello " + name
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := easycel.NewReloadableEnvironment(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := env.Program(`greet("a")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = registry.ReplaceFunction("greet", func(name string) string {
+		return "hi " + name
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = env.Reload(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := env.Program(`greet("a")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for program, want := range map[cel.Program]string{old: "hello a", current: "hi a"} {
+		got, err := easycel.EvalString(program, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+
+	err = registry.UnregisterFunction("greet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = env.Reload(registry)
+	var reloadErr *easycel.ReloadError
+	if !errors.As(err, &reloadErr) {
+		t.Fatalf("expected reload error, got %v", err)
+	}
+	if len(reloadErr.Failures) != 1 || reloadErr.Failures[0].Source != `greet("a")` {
+		t.Errorf("got failures %v", reloadErr.Failures)
+	}
+	var diags *easycel.Diagnostics
+	if !errors.As(err, &diags) {
+		t.Errorf("expected diagnostics of the failure, got %v", err)
+	}
+	if _, err := env.Program(`greet("b")`); err != nil {
+		t.Errorf("expected the registry to be kept, got %v", err)
+	}
+
+	env.Forget(`greet("a")`)
+	env.Forget(`greet("b")`)
+	err = env.Reload(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.Program(`greet("a")`); err == nil {
+		t.Errorf("expected the registry to be swapped")
+	}
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	type AccountV1 struct {
+		Name  string `json:"name"`
+		Age   int    `json:"age"`
+		Email string `json:"email"`
+	}
+	type AccountV2 struct {
+		Name string `json:"full_name"`
+		Age  string `json:"age"`
+	}
+	newRegistry := func(account any, greet any) *easycel.Registry {
+		registry := easycel.NewRegistry("test", easycel.WithTagName("json"))
+		err := registry.RegisterTypeAs("acme.Account", account)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = registry.RegisterVariable("account", account)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = registry.RegisterFunction("greet", greet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return registry
+	}
+	oldRegistry := newRegistry(AccountV1{}, func(name string) string { return name })
+	updatedRegistry := newRegistry(AccountV2{}, func(name string, n int) string { return name })
+
+	report, err := easycel.CheckCompatibility(oldRegistry, updatedRegistry, []string{
+		`account.name`,
+		`account.age`,
+		`greet("a")`,
+		`size("a")`,
+		`undeclared`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `expression "account.name": fails to compile: ERROR: :1:8: undefined field 'name', did you mean 'age'?
+ | account.name
+ | .......^
+expression "account.age": output type changed from int to string
+expression "greet(\"a\")": fails to compile: ERROR: :1:6: found no matching overload for 'greet' applied to '(string)'
+ | greet("a")
+ | .....^
+renamed field acme.Account.name: name -> full_name
+changed field type acme.Account.age: int -> string
+removed field acme.Account.email: string
+removed overload greet|@|string|string: greet(string) -> string
+`
+	if got := report.String(); got != want {
+		t.Errorf("got report\n%s\nwant\n%s", got, want)
+	}
+	if !report.Breaking() {
+		t.Errorf("expected breaking report")
+	}
+}
+
+func TestOverloadCollision(t *testing.T) {
+	registry := easycel.NewRegistry("test")
+	err := registry.RegisterFunction("kind", func(n int) string {
+		return "int"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterFunction("kind", func(n int64) string {
+		return "int64"
+	})
+	want := "function kind: overload kind|@|int|string of func(int64) string has the same CEL argument types as overload kind|@|int|string of func(int) string"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("expected collision error, got %v", err)
+	}
+	err = registry.RegisterFunction("kind", func(n int64) string {
+		return "int64"
+	}, easycel.WithPreferred(), easycel.WithOverloadID("kind_int64"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterFunction("kind", func(n int32) string {
+		return "int32"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterFunction("kind", func(s string) string {
+		return "string"
+	}, easycel.WithOverloadID("kind_int64"))
+	want = "function kind: overload ID kind_int64 already used by overload kind_int64 of kind by func(int64) string"
+	if err == nil || err.Error() != want {
+		t.Errorf("expected error for duplicate overload ID, got %v", err)
+	}
+	err = registry.RegisterFunction("kind", func(s string) string {
+		return "string"
+	}, easycel.WithOverloadID("kind_string"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterFunction("kind", func(n int32) string {
+		return "int32"
+	}, easycel.WithPreferred(), easycel.WithOverloadID("kind_string"))
+	want = "function kind: overload ID kind_string already used by overload kind_string of kind by func(string) string"
+	if err == nil || err.Error() != want {
+		t.Errorf("expected error naming the overload of the ID, got %v", err)
+	}
+	err = registry.RegisterFunction("other", func(s string) string {
+		return "string"
+	}, easycel.WithOverloadID("kind_int64"))
+	want = "function other: overload ID kind_int64 already used by overload kind_int64 of kind by func(int64) string"
+	if err == nil || err.Error() != want {
+		t.Errorf("expected error for overload ID of another function, got %v", err)
+	}
+	err = registry.RegisterFunction("none", func() string {
+		return ""
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterFunction("none", func() int64 {
+		return 0
+	})
+	want = "function none: overload none|@||int of func() int64 has the same CEL argument types as overload none|@||string of func() string"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("expected collision error for functions without arguments, got %v", err)
+	}
+
+	functions, err := registry.Functions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(functions) != 2 || len(functions[0].Overloads) != 2 || functions[0].Overloads[0].ID != "kind_int64" {
+		t.Fatalf("expected the preferred overload only, got %v", functions)
+	}
+	env, err := easycel.NewEnvironment(cel.Lib(registry))
+	if err != nil {
+		t.Fatal(err)
+	}
+	program, err := env.Program("kind(1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := easycel.EvalString(program, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "int64" {
+		t.Errorf("got %q, want the preferred overload", got)
+	}
+}
+
+// slowValidator delays checking sources, widening the window between compiling and recording a source.
+// blockingValidator blocks the first compile after it is armed until released.
+type blockingValidator struct {
+	armed   atomic.Bool
+	entered chan struct{}
+	release chan struct{}
+}
+
+func newBlockingValidator() *blockingValidator {
+	return &blockingValidator{
+		entered: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+}
+
+func (v *blockingValidator) Name() string {
+	return "blocking"
+}
+
+func (v *blockingValidator) Validate(*cel.Env, cel.ValidatorConfig, *ast.AST, *cel.Issues) {
+	if v.armed.CompareAndSwap(true, false) {
+		v.entered <- struct{}{}
+		<-v.release
+	}
+}
+
+func TestReloadableEnvironmentConcurrent(t *testing.T) {
+	registry := easycel.NewRegistry("test")
+	err := registry.RegisterFunction("greet", func(name string) string {
+		return "hello " + name
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("reload", func(t *testing.T) {
+		validator := newBlockingValidator()
+		env, err := easycel.NewReloadableEnvironment(registry, cel.ASTValidators(validator))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = env.Program(`greet("a")`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		validator.armed.Store(true)
+		reloadErr := make(chan error)
+		go func() {
+			reloadErr <- env.Reload(registry)
+		}()
+		<-validator.entered
+
+		// Programs are returned while the reload checks the sources.
+		_, err = env.Program(`greet("a")`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = env.Program(`greet("b")`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		close(validator.release)
+		err = <-reloadErr
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The source recorded while reloading is checked by the reload.
+		err = env.Reload(easycel.NewRegistry("test"))
+		var failures *easycel.ReloadError
+		if !errors.As(err, &failures) || len(failures.Failures) != 2 {
+			t.Fatalf("expected reload error for both sources, got %v", err)
+		}
+	})
+
+	t.Run("swap", func(t *testing.T) {
+		validator := newBlockingValidator()
+		env, err := easycel.NewReloadableEnvironment(registry, cel.ASTValidators(validator))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		validator.armed.Store(true)
+		programErr := make(chan error)
+		go func() {
+			_, err := env.Program(`greet("a")`)
+			programErr <- err
+		}()
+		<-validator.entered
+
+		err = env.Reload(easycel.NewRegistry("test"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		close(validator.release)
+		// The source compiled while the registry is swapped in is compiled again with it.
+		err = <-programErr
+		if err == nil || !strings.Contains(err.Error(), "undeclared reference to 'greet'") {
+			t.Errorf("expected compile error with the reloaded registry, got %v", err)
+		}
+	})
+}
+
+type Message struct {
+	Meta    Meta       `json:"meta"`
+	Message string     `json:"message"`
+	Next    *Message   `json:"next"`
+	Time    Timestamp  `json:"time"`
+	List    []*Message `json:"list"`
+	_       int
+}
+
+type Shape interface {
+	Area() float64
+}
+
+type Circle struct {
+	Name   string  `json:"name"`
+	Radius float64 `json:"radius"`
+}
+
+func (c Circle) Area() float64 {
+	return 3.14 * c.Radius * c.Radius
+}
+
+type Square struct {
+	Name string  `json:"name"`
+	Side float64 `json:"side"`
+}
+
+func (s Square) Area() float64 {
+	return s.Side * s.Side
+}
+
+type Drawing struct {
+	Shape  Shape   `json:"shape"`
+	Shapes []Shape `json:"shapes"`
+}
+
+type Digest struct {
+	Hash [4]byte `json:"hash"`
+	IDs  [2]int  `json:"ids"`
+}
+
+type Counter struct {
+	Count int `json:"count"`
+	Limit int `json:"limit,omitempty"`
 }
 
 type Meta struct {
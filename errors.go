@@ -0,0 +1,135 @@
+package easycel
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/interpreter"
+)
+
+// FunctionError is an error returned by a registered Go function.
+type FunctionError struct {
+	// Function is the CEL function name.
+	Function string
+	// Overload is the ID of the overload that was called.
+	Overload string
+	// Err is the error returned by the Go function.
+	Err error
+
+	// exprID is the call which failed, set by the decorator of the program.
+	exprID int64
+}
+
+// Error implements the error interface.
+func (e *FunctionError) Error() string {
+	return fmt.Sprintf("%s (%s): %v", e.Function, e.Overload, e.Err)
+}
+
+// Unwrap returns the error returned by the Go function.
+func (e *FunctionError) Unwrap() error {
+	return e.Err
+}
+
+// EvalError is a runtime error positioned at the expression which failed.
+type EvalError struct {
+	// Line is 1-based and Column 0-based, Offset is the offset in runes from the start of the source.
+	Line   int
+	Column int
+	Offset int
+	// Snippet is the line of the source containing the failed expression.
+	Snippet string
+	// Function and Overload are set if a registered Go function failed.
+	Function string
+	Overload string
+	// Err is the underlying error, the error returned by the Go function if any.
+	Err error
+
+	display string
+}
+
+// Error implements the error interface.
+func (e *EvalError) Error() string {
+	return e.display
+}
+
+// Unwrap returns the underlying error.
+func (e *EvalError) Unwrap() error {
+	return e.Err
+}
+
+// newEvalError positions the evaluation error in the source of the AST,
+// errors without a known expression are returned unchanged.
+func newEvalError(ast *cel.Ast, err error) error {
+	var celErr *types.Err
+	if !errors.As(err, &celErr) {
+		return err
+	}
+	// Errors passing through the accumulator of a comprehension are labeled with it,
+	// the failed call of a Go function is known from the function error.
+	id := celErr.NodeID()
+	var funcErr *FunctionError
+	if errors.As(celErr, &funcErr) && funcErr.exprID != 0 {
+		id = funcErr.exprID
+	}
+	if id == 0 {
+		return err
+	}
+	info := ast.NativeRep().SourceInfo()
+	offset, ok := info.GetOffsetRange(id)
+	if !ok {
+		return err
+	}
+	loc := info.GetStartLocation(id)
+	source := ast.Source()
+
+	evalErr := &EvalError{
+		Line:   loc.Line(),
+		Column: loc.Column(),
+		Offset: int(offset.Start),
+		Err:    celErr.Unwrap(),
+	}
+	evalErr.Snippet, _ = source.Snippet(loc.Line())
+
+	message := celErr.Error()
+	if funcErr != nil {
+		evalErr.Function = funcErr.Function
+		evalErr.Overload = funcErr.Overload
+		evalErr.Err = funcErr.Err
+	}
+	evalErr.display = (&common.Error{
+		Location: loc,
+		Message:  message,
+		ExprID:   id,
+	}).ToDisplayString(source)
+	return evalErr
+}
+
+// decorateFunctionErrors wraps the calls to record the call failing with a function error,
+// which is the innermost as the calls of the arguments are evaluated first.
+func decorateFunctionErrors(i interpreter.Interpretable) (interpreter.Interpretable, error) {
+	if call, ok := i.(interpreter.InterpretableCall); ok {
+		return &functionErrorCall{InterpretableCall: call}, nil
+	}
+	return i, nil
+}
+
+// functionErrorCall is a call recording itself in the function errors it fails with.
+type functionErrorCall struct {
+	interpreter.InterpretableCall
+}
+
+// Eval implements the interpreter.Interpretable interface method.
+func (c *functionErrorCall) Eval(activation interpreter.Activation) ref.Val {
+	val := c.InterpretableCall.Eval(activation)
+	if celErr, ok := val.(*types.Err); ok {
+		var funcErr *FunctionError
+		if errors.As(celErr, &funcErr) && funcErr.exprID == 0 {
+			funcErr.exprID = c.ID()
+		}
+	}
+	return val
+}
@@ -0,0 +1,315 @@
+package easycel
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/decls"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/interpreter"
+)
+
+// Explanation is the annotated tree of an evaluated expression.
+type Explanation struct {
+	// Value is the result of the evaluation.
+	Value ref.Val `json:"-"`
+	// Root is the node of the whole expression.
+	Root *ExplainNode `json:"root"`
+}
+
+// ExplainNode is a sub-expression with the value it evaluated to.
+type ExplainNode struct {
+	ID int64 `json:"id"`
+	// Expr is the source of the sub-expression.
+	Expr string `json:"expr"`
+	// Function is the name of the called function and Overload the overload which ran,
+	// set only for calls. Candidates are the overloads left by the type checker if there are several,
+	// of which the one which ran is only known for registered Go funcs.
+	Function   string   `json:"function,omitempty"`
+	Overload   string   `json:"overload,omitempty"`
+	Candidates []string `json:"candidates,omitempty"`
+	// Evaluated is false if the sub-expression was skipped, e.g. by short-circuiting.
+	Evaluated bool `json:"evaluated"`
+	// Type is the runtime type and Value the formatted Go value of the result,
+	// Error is set instead if the sub-expression failed.
+	Type     string         `json:"type,omitempty"`
+	Value    string         `json:"value,omitempty"`
+	Error    string         `json:"error,omitempty"`
+	Children []*ExplainNode `json:"children,omitempty"`
+}
+
+// Explain evaluates the source and records the value of every sub-expression,
+// the vars may be nil, a map or an interpreter.Activation.
+// Sub-expressions of comprehensions are recorded with their values of the last iteration.
+// Evaluation errors are recorded in the explanation rather than returned.
+func (e *Environment) Explain(src string, vars any) (*Explanation, error) {
+	env, err := e.explainEnvironment()
+	if err != nil {
+		return nil, err
+	}
+	checked, err := env.Compile(src)
+	if err != nil {
+		return nil, err
+	}
+	observer := &overloadObserver{overloads: map[int64]string{}}
+	observed := env.env
+	if registry := e.registry(); registry != nil {
+		opts, err := registry.observedFunctions(env.env, observer.observe)
+		if err != nil {
+			return nil, err
+		}
+		observed, err = env.env.Extend(opts...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	program, err := observed.Program(checked,
+		cel.EvalOptions(cel.OptTrackState),
+		cel.CustomDecorator(observer.decorate),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if vars == nil {
+		vars = map[string]any{}
+	}
+
+	out, details, err := program.Eval(vars)
+	if details == nil {
+		return nil, err
+	}
+
+	native := checked.NativeRep()
+	x := &explainer{
+		info:      native.SourceInfo(),
+		refs:      native.ReferenceMap(),
+		state:     details.State(),
+		overloads: observer.overloads,
+	}
+	root := x.explain(native.Expr())
+	return &Explanation{
+		Value: out,
+		Root:  root,
+	}, nil
+}
+
+// explainEnvironment returns the environment tracking macro calls,
+// so that sub-expressions can be turned back into source.
+func (e *Environment) explainEnvironment() (*Environment, error) {
+	e.explainOnce.Do(func() {
+		var env *cel.Env
+		env, e.explainErr = e.env.Extend(cel.EnableMacroCallTracking())
+		e.explain = &Environment{env: env}
+	})
+	return e.explain, e.explainErr
+}
+
+// observedFunctions declares the Go funcs of the registry declared by the environment again,
+// reporting their overload ID to observe when they run.
+func (r *Registry) observedFunctions(env *cel.Env, observe func(id string)) ([]cel.EnvOption, error) {
+	declared := env.Functions()
+	var opts []cel.EnvOption
+	for _, name := range sortedKeys(r.funcs) {
+		decl, ok := declared[name]
+		if !ok {
+			continue
+		}
+		ids := map[string]struct{}{}
+		for _, o := range decl.OverloadDecls() {
+			ids[o.ID()] = struct{}{}
+		}
+		var fnOpts []cel.FunctionOpt
+		disableTypeGuards := false
+		for _, o := range r.funcs[name].overloads {
+			if _, ok := ids[o.id]; !ok || !o.fun.IsValid() {
+				continue
+			}
+			config := o.config
+			config.overloadID = o.id
+			observed, err := r.newFuncOverload(name, observedFunc(o.id, o.fun, observe), o.member, config)
+			if err != nil {
+				return nil, err
+			}
+			fnOpts = append(fnOpts, observed.opt)
+			disableTypeGuards = disableTypeGuards || o.disableTypeGuards
+		}
+		if len(fnOpts) == 0 {
+			continue
+		}
+		if disableTypeGuards {
+			fnOpts = append(fnOpts, decls.DisableTypeGuards(true))
+		}
+		opts = append(opts, cel.Function(name, fnOpts...))
+	}
+	return opts, nil
+}
+
+// observedFunc returns a func of the same type reporting the overload ID before calling fun.
+func observedFunc(id string, fun reflect.Value, observe func(id string)) reflect.Value {
+	return reflect.MakeFunc(fun.Type(), func(args []reflect.Value) []reflect.Value {
+		observe(id)
+		return fun.Call(args)
+	})
+}
+
+// overloadObserver records the overload each call ran, of the last evaluation of the call.
+type overloadObserver struct {
+	// ran is the overload reported by the Go func of the call being evaluated.
+	ran       string
+	overloads map[int64]string
+}
+
+func (o *overloadObserver) observe(id string) {
+	o.ran = id
+}
+
+// decorate wraps the calls to attribute the overload reported while evaluating them,
+// calls of the arguments are evaluated and attributed before the Go func of the call runs.
+func (o *overloadObserver) decorate(i interpreter.Interpretable) (interpreter.Interpretable, error) {
+	if call, ok := i.(interpreter.InterpretableCall); ok {
+		return &observedCall{InterpretableCall: call, observer: o}, nil
+	}
+	return i, nil
+}
+
+// observedCall is a call attributing the overload it ran.
+type observedCall struct {
+	interpreter.InterpretableCall
+	observer *overloadObserver
+}
+
+// Eval implements the interpreter.Interpretable interface method.
+func (c *observedCall) Eval(activation interpreter.Activation) ref.Val {
+	c.observer.ran = ""
+	val := c.InterpretableCall.Eval(activation)
+	if c.observer.ran != "" {
+		c.observer.overloads[c.ID()] = c.observer.ran
+		c.observer.ran = ""
+	}
+	return val
+}
+
+// explainer builds the explanation of an evaluated AST.
+type explainer struct {
+	info  *ast.SourceInfo
+	refs  map[int64]*ast.ReferenceInfo
+	state interpreter.EvalState
+	// overloads are the overloads observed to run by call ID.
+	overloads map[int64]string
+}
+
+func (x *explainer) explain(expr ast.Expr) *ExplainNode {
+	node := &ExplainNode{
+		ID: expr.ID(),
+	}
+	node.Expr, _ = cel.ExprToString(expr, x.info)
+
+	if val, ok := x.state.Value(expr.ID()); ok {
+		node.Evaluated = true
+		if types.IsError(val) {
+			node.Error = val.(*types.Err).String()
+		} else {
+			node.Type = val.Type().TypeName()
+			node.Value = formatValue(val)
+		}
+	}
+
+	var children []ast.Expr
+	switch expr.Kind() {
+	case ast.CallKind:
+		call := expr.AsCall()
+		node.Function = call.FunctionName()
+		if call.IsMemberFunction() {
+			children = append(children, call.Target())
+		}
+		children = append(children, call.Args()...)
+		node.Overload = x.overloads[expr.ID()]
+		if ref, ok := x.refs[expr.ID()]; ok {
+			switch {
+			case len(ref.OverloadIDs) > 1:
+				node.Candidates = ref.OverloadIDs
+			case len(ref.OverloadIDs) == 1 && node.Overload == "":
+				node.Overload = ref.OverloadIDs[0]
+			}
+		}
+	case ast.SelectKind:
+		// Attributes like a.b.c are resolved as a whole, leaving no value for the operands.
+		operand := expr.AsSelect().Operand()
+		if _, ok := x.state.Value(operand.ID()); ok || !node.Evaluated {
+			children = append(children, operand)
+		}
+	case ast.ListKind:
+		children = append(children, expr.AsList().Elements()...)
+	case ast.MapKind:
+		for _, entry := range expr.AsMap().Entries() {
+			children = append(children, entry.AsMapEntry().Key(), entry.AsMapEntry().Value())
+		}
+	case ast.StructKind:
+		for _, field := range expr.AsStruct().Fields() {
+			children = append(children, field.AsStructField().Value())
+		}
+	case ast.ComprehensionKind:
+		comp := expr.AsComprehension()
+		children = append(children, comp.IterRange(), comp.LoopCondition(), comp.LoopStep(), comp.Result())
+	}
+	for _, child := range children {
+		node.Children = append(node.Children, x.explain(child))
+	}
+	return node
+}
+
+func formatValue(val ref.Val) string {
+	switch v := val.Value().(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []byte:
+		return fmt.Sprintf("b%q", v)
+	}
+	if types.IsUnknown(val) {
+		return "unknown"
+	}
+	if val == types.NullValue {
+		return "null"
+	}
+	return fmt.Sprintf("%+v", val.Value())
+}
+
+// String renders the explanation as an annotated tree.
+func (x *Explanation) String() string {
+	var sb strings.Builder
+	x.Root.write(&sb, "", "")
+	return sb.String()
+}
+
+// JSON renders the explanation as indented JSON.
+func (x *Explanation) JSON() ([]byte, error) {
+	return json.MarshalIndent(x, "", "  ")
+}
+
+func (n *ExplainNode) write(sb *strings.Builder, prefix, childPrefix string) {
+	sb.WriteString(prefix)
+	sb.WriteString(n.Expr)
+	switch {
+	case !n.Evaluated:
+		sb.WriteString(" => <not evaluated>")
+	case n.Error != "":
+		sb.WriteString(" => error: ")
+		sb.WriteString(n.Error)
+	default:
+		sb.WriteString(" => ")
+		sb.WriteString(n.Value)
+	}
+	sb.WriteString("\n")
+	for i, child := range n.Children {
+		if i == len(n.Children)-1 {
+			child.write(sb, childPrefix+"└── ", childPrefix+"    ")
+		} else {
+			child.write(sb, childPrefix+"├── ", childPrefix+"│   ")
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package easycel
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+)
+
+// Fingerprint returns a stable hash of the declarations of the registry,
+// the types, functions with their Go funcs, variables, conversions and options affecting compilation,
+// which is the same for registries declaring the same in any order.
+// It can be used as a cache key for programs compiled with the registry.
+// Go funcs are told apart by name, closures of the same func literal capturing other values are not.
+func (r *Registry) Fingerprint() (string, error) {
+	h := sha256.New()
+	err := r.writeDeclarations(h)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeDeclarations writes a canonical description of the declarations of the registry.
+func (r *Registry) writeDeclarations(w io.Writer) error {
+	fmt.Fprintf(w, "tag name %q\n", r.tagName)
+	fmt.Fprintf(w, "container %q\n", r.container)
+	fmt.Fprintf(w, "presence %d\n", r.presence)
+	fmt.Fprintf(w, "cost limit %d\n", r.costLimit)
+
+	for _, t := range r.Types() {
+		fmt.Fprintf(w, "type %s %v %v\n", t.Name, t.GoType, t.Implementations)
+		for _, field := range t.Fields {
+			fmt.Fprintf(w, "\tfield %s %s\n", field.Name, field.Type)
+		}
+	}
+
+	celTypes := make([]string, 0, len(r.refTypes))
+	for _, t := range r.refTypes {
+		celTypes = append(celTypes, t.TypeName())
+	}
+	sort.Strings(celTypes)
+	for _, name := range celTypes {
+		fmt.Fprintf(w, "cel type %s\n", name)
+	}
+
+	for _, conv := range r.Conversions() {
+		fmt.Fprintf(w, "conversion %v -> %v\n", conv.From, conv.To)
+	}
+
+	functions, err := r.Functions()
+	if err != nil {
+		return err
+	}
+	for _, fn := range functions {
+		fmt.Fprintf(w, "function %s\n", fn.Name)
+		for _, o := range fn.Overloads {
+			fmt.Fprintf(w, "\toverload %s %s\n", o.ID, o.Signature())
+			registered := r.overloads[o.ID]
+			if registered.fun.IsValid() {
+				fmt.Fprintf(w, "\t\tfunc %s\n", runtime.FuncForPC(registered.fun.Pointer()).Name())
+			}
+			if cost := registered.config.cost; cost != nil {
+				fmt.Fprintf(w, "\t\tcost %d %g\n", cost.fixed, cost.perSize)
+			}
+		}
+	}
+
+	for _, v := range r.Variables() {
+		fmt.Fprintf(w, "variable %s %s\n", v.Name, v.Type)
+	}
+	return nil
+}
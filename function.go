@@ -0,0 +1,206 @@
+package easycel
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/decls"
+)
+
+// function is a declared function with its overloads in registration order.
+type function struct {
+	name      string
+	overloads []*overload
+}
+
+// overload is an overload of a function,
+// fun is unset for the operators of registered CEL values.
+type overload struct {
+	id string
+	// name is the function of the overload, set when it is added.
+	name   string
+	opt    cel.FunctionOpt
+	fun    reflect.Value
+	member bool
+	// argTypes are the CEL argument types of Go funcs, e.g. "int,string",
+	// overloads with the same can't be told apart.
+	argTypes          string
+	config            functionConfig
+	disableTypeGuards bool
+	// inherited is set for the overloads of a registry created by Extend,
+	// which are replaced when registered again.
+	inherited bool
+	// origin is the registered overload of copies made by Clone, Extend and Merge.
+	origin *overload
+}
+
+// registered returns the overload as registered, of which the overload may be a copy.
+func (o *overload) registered() *overload {
+	if o.origin != nil {
+		return o.origin
+	}
+	return o
+}
+
+// sameArgTypes reports whether both overloads are Go funcs with the same CEL argument types.
+func (o *overload) sameArgTypes(other *overload) bool {
+	return o.fun.IsValid() && other.fun.IsValid() &&
+		o.member == other.member && o.argTypes == other.argTypes
+}
+
+// String describes the overload in errors, e.g. "overload greet|@|int|string of greet by func(int) string".
+func (o *overload) String() string {
+	if goType := o.goType(); goType != nil {
+		return fmt.Sprintf("overload %s of %s by %v", o.id, o.name, goType)
+	}
+	return fmt.Sprintf("overload %s of %s", o.id, o.name)
+}
+
+// goType returns the type of the Go func, nil for operators.
+func (o *overload) goType() reflect.Type {
+	if !o.fun.IsValid() {
+		return nil
+	}
+	return o.fun.Type()
+}
+
+// options returns the declaration of the function.
+func (f *function) options() []cel.FunctionOpt {
+	opts := make([]cel.FunctionOpt, 0, len(f.overloads)+2)
+	disableTypeGuards := false
+	for _, o := range f.overloads {
+		opts = append(opts, o.opt)
+		disableTypeGuards = disableTypeGuards || o.disableTypeGuards
+	}
+	if disableTypeGuards {
+		opts = append(opts, decls.DisableTypeGuards(true))
+	}
+	if docs := f.docs(); len(docs) != 0 {
+		opts = append(opts, cel.FunctionDocs(docs...))
+	}
+	return opts
+}
+
+// docs returns the distinct descriptions and deprecation notices of the overloads.
+func (f *function) docs() []string {
+	docs := []string{}
+	seen := map[string]struct{}{}
+	for _, o := range f.overloads {
+		deprecated := ""
+		if o.config.deprecated != "" {
+			deprecated = "Deprecated: " + o.config.deprecated
+		}
+		for _, doc := range []string{o.config.description, deprecated} {
+			if _, ok := seen[doc]; ok || doc == "" {
+				continue
+			}
+			seen[doc] = struct{}{}
+			docs = append(docs, doc)
+		}
+	}
+	return docs
+}
+
+// addOverload adds the overload to the function, replacing an inherited overload with the same ID or argument types.
+// Of overloads with the same argument types the one registered WithPreferred is kept,
+// otherwise an error naming the existing overload is returned.
+func (r *Registry) addOverload(name string, o *overload) error {
+	err := r.checkOverloadID(name, o)
+	if err != nil {
+		return err
+	}
+	existing := r.collidingOverload(name, o)
+	if existing != nil && !existing.inherited {
+		switch {
+		case o.config.preferred && !existing.config.preferred:
+			// Replaced below.
+		case existing.config.preferred && !o.config.preferred:
+			return nil
+		case existing.id == o.id && existing.goType() == o.goType():
+			return fmt.Errorf("function %s: overload %s already registered by %v", name, existing.id, existing.goType())
+		default:
+			return fmt.Errorf("function %s: overload %s of %v has the same CEL argument types as overload %s of %v, prefer one WithPreferred",
+				name, o.id, o.goType(), existing.id, existing.goType())
+		}
+	}
+	r.replaceOverload(name, o)
+	return nil
+}
+
+// checkOverloadID returns an error naming the overload the ID is used by,
+// unless it is an overload of the function with the same argument types or inherited, which are replaced.
+func (r *Registry) checkOverloadID(name string, o *overload) error {
+	owner, ok := r.overloads[o.id]
+	if !ok {
+		return nil
+	}
+	if owner.name == name && (owner.inherited || owner.sameArgTypes(o)) {
+		return nil
+	}
+	if owner.name == name && !owner.fun.IsValid() && !o.fun.IsValid() {
+		return fmt.Errorf("function %s: overload %s already registered", name, o.id)
+	}
+	return fmt.Errorf("function %s: overload ID %s already used by %s", name, o.id, owner)
+}
+
+// collidingOverload returns the overload of the function with the same argument types.
+func (r *Registry) collidingOverload(name string, o *overload) *overload {
+	fn, ok := r.funcs[name]
+	if !ok {
+		return nil
+	}
+	for _, existing := range fn.overloads {
+		if existing.sameArgTypes(o) {
+			return existing
+		}
+	}
+	return nil
+}
+
+// replaceOverload adds the overload to the function, replacing any overload with the same ID or argument types.
+func (r *Registry) replaceOverload(name string, o *overload) {
+	if existing := r.collidingOverload(name, o); existing != nil && existing.id != o.id {
+		r.removeOverload(name, existing.id)
+	}
+	fn, ok := r.funcs[name]
+	if !ok {
+		fn = &function{name: name}
+		r.funcs[name] = fn
+	}
+	o.name = name
+	r.overloads[o.id] = o
+	for i, existing := range fn.overloads {
+		if existing.id == o.id {
+			fn.overloads[i] = o
+			return
+		}
+	}
+	fn.overloads = append(fn.overloads, o)
+}
+
+// removeOverload removes the overload from the function, and the function if it has no overloads left.
+func (r *Registry) removeOverload(name, id string) {
+	delete(r.overloads, id)
+	fn := r.funcs[name]
+	for i, existing := range fn.overloads {
+		if existing.id == id {
+			fn.overloads = append(fn.overloads[:i], fn.overloads[i+1:]...)
+			break
+		}
+	}
+	if len(fn.overloads) == 0 {
+		delete(r.funcs, name)
+	}
+}
+
+// costOverloads returns the overloads with a cost sorted by ID.
+func (r *Registry) costOverloads() []*overload {
+	var out []*overload
+	for _, id := range sortedKeys(r.overloads) {
+		if o := r.overloads[id]; o.config.cost != nil {
+			out = append(out, o)
+		}
+	}
+	return out
+}
@@ -0,0 +1,87 @@
+package easycel
+
+// FunctionOption configures a function or method at registration time.
+type FunctionOption func(*functionConfig)
+
+type functionConfig struct {
+	cost       *functionCost
+	overloadID string
+	preferred  bool
+	overloadMeta
+}
+
+// overloadMeta is the documentation of an overload.
+type overloadMeta struct {
+	description string
+	paramNames  []string
+	examples    []string
+	deprecated  string
+}
+
+// WithDescription sets the description of the overload,
+// declared as the documentation of the CEL function and shown in the generated reference.
+func WithDescription(description string) FunctionOption {
+	return func(c *functionConfig) {
+		c.description = description
+	}
+}
+
+// WithParamNames names the arguments of the overload, for methods the arguments after the receiver.
+func WithParamNames(names ...string) FunctionOption {
+	return func(c *functionConfig) {
+		c.paramNames = names
+	}
+}
+
+// WithExamples sets examples of calls of the overload, declared as the CEL overload examples.
+func WithExamples(examples ...string) FunctionOption {
+	return func(c *functionConfig) {
+		c.examples = examples
+	}
+}
+
+// WithDeprecated marks the overload as deprecated with a notice, e.g. what to use instead.
+func WithDeprecated(notice string) FunctionOption {
+	return func(c *functionConfig) {
+		c.deprecated = notice
+	}
+}
+
+// WithOverloadID sets the ID of the overload instead of the one derived from its CEL signature,
+// e.g. "greet_int" instead of "greet|@|int|string".
+func WithOverloadID(id string) FunctionOption {
+	return func(c *functionConfig) {
+		c.overloadID = id
+	}
+}
+
+// WithPreferred prefers the overload over others of the function with the same CEL argument types,
+// e.g. registered from func(int) string and func(int64) string which are both declared as greet(int).
+// The preferred overload replaces the others whether it is registered first or last.
+func WithPreferred() FunctionOption {
+	return func(c *functionConfig) {
+		c.preferred = true
+	}
+}
+
+// WithCost sets a fixed cost for each call of the function,
+// used by cost estimation and runtime cost limits.
+func WithCost(cost uint64) FunctionOption {
+	return func(c *functionConfig) {
+		if c.cost == nil {
+			c.cost = &functionCost{}
+		}
+		c.cost.fixed = cost
+	}
+}
+
+// WithSizeCost sets a cost per unit of size of the sized arguments (strings, bytes, lists and maps)
+// for each call of the function, added to the fixed cost.
+func WithSizeCost(costPerUnit float64) FunctionOption {
+	return func(c *functionConfig) {
+		if c.cost == nil {
+			c.cost = &functionCost{}
+		}
+		c.cost.perSize = costPerUnit
+	}
+}
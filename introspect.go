@@ -0,0 +1,216 @@
+package easycel
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/decls"
+)
+
+// TypeInfo describes a native type registered with the registry.
+type TypeInfo struct {
+	// Name is the CEL type name.
+	Name   string
+	GoType reflect.Type
+	// Fields are the fields selectable on the type, for interfaces the fields shared by all implementations.
+	Fields []*FieldInfo
+	// Implementations are the CEL type names of the implementations of an interface.
+	Implementations []string
+}
+
+// docTagName is the struct tag documenting fields and variables.
+const docTagName = "doc"
+
+// FieldInfo describes a field of a registered type.
+type FieldInfo struct {
+	// Name is the field name derived from the tag.
+	Name string
+	Type *cel.Type
+	// GoType is nil for the fields of interfaces.
+	GoType reflect.Type
+	// Doc is read from the doc tag of the struct field, e.g. `doc:"the name"`.
+	Doc string
+
+	goName string
+}
+
+// VariableInfo describes a variable declared with the registry.
+type VariableInfo struct {
+	Name string
+	Type *cel.Type
+	// GoType is nil if the variable was declared by CEL type.
+	GoType reflect.Type
+	// Lazy is true for variables registered with RegisterLazyVariable.
+	Lazy bool
+	// Doc is read from the doc tag of the fields of the input struct.
+	Doc string
+}
+
+// FunctionInfo describes a function or method declared with the registry.
+type FunctionInfo struct {
+	Name      string
+	Overloads []*OverloadInfo
+}
+
+// OverloadInfo describes an overload of a function.
+type OverloadInfo struct {
+	ID string
+	// Member is true for methods, the first argument is the receiver.
+	Member     bool
+	ArgTypes   []*cel.Type
+	ResultType *cel.Type
+	// GoType is the registered Go func, nil for the operators of registered CEL values.
+	GoType reflect.Type
+	// Description, ParamNames, Examples and Deprecated are set with the options
+	// WithDescription, WithParamNames, WithExamples and WithDeprecated.
+	Description string
+	ParamNames  []string
+	Examples    []string
+	Deprecated  string
+
+	name string
+}
+
+// Signature returns the CEL signature of the overload, e.g. "string.greet(int) -> string",
+// or "string.greet(count int) -> string" with param names.
+func (o *OverloadInfo) Signature() string {
+	args := make([]string, 0, len(o.ArgTypes))
+	for i, arg := range o.ArgTypes {
+		param := i
+		if o.Member {
+			param--
+		}
+		if param >= 0 && param < len(o.ParamNames) {
+			args = append(args, o.ParamNames[param]+" "+arg.String())
+		} else {
+			args = append(args, arg.String())
+		}
+	}
+	if o.Member && len(args) != 0 {
+		return args[0] + "." + o.name + "(" + strings.Join(args[1:], ", ") + ") -> " + o.ResultType.String()
+	}
+	return o.name + "(" + strings.Join(args, ", ") + ") -> " + o.ResultType.String()
+}
+
+// ConversionInfo describes a conversion registered with RegisterConversion.
+type ConversionInfo struct {
+	// From and To are the Go types converted, one of them a CEL value.
+	From reflect.Type
+	To   reflect.Type
+}
+
+// Types returns the registered native types sorted by name.
+func (r *Registry) Types() []*TypeInfo {
+	tp := r.nativeTypeProvider
+	infos := make([]*TypeInfo, 0, len(tp.nativeTypes))
+	for _, name := range sortedKeys(tp.nativeTypes) {
+		t := tp.nativeTypes[name]
+		info := &TypeInfo{
+			Name:   name,
+			GoType: t.GetRawType(),
+		}
+		if it, ok := t.(*interfaceType); ok {
+			for _, impl := range it.Implementations() {
+				info.Implementations = append(info.Implementations, tp.typeName(impl))
+			}
+		}
+		fieldNames, _ := tp.FindStructFieldNames(name)
+		for _, fieldName := range fieldNames {
+			fieldType, ok := tp.FindStructFieldType(name, fieldName)
+			if !ok {
+				continue
+			}
+			field := &FieldInfo{
+				Name: fieldName,
+				Type: fieldType.Type,
+			}
+			if rawType := t.GetRawType(); rawType.Kind() == reflect.Struct {
+				structField := rawType.Field(getStructFieldMap(rawType, t.TagName())[fieldName])
+				field.GoType = structField.Type
+				field.goName = structField.Name
+				field.Doc = structField.Tag.Get(docTagName)
+			}
+			info.Fields = append(info.Fields, field)
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// Variables returns the declared variables sorted by name.
+func (r *Registry) Variables() []*VariableInfo {
+	infos := make([]*VariableInfo, 0, len(r.variables))
+	for _, name := range sortedKeys(r.variables) {
+		_, lazy := r.lazyVariables[name]
+		infos = append(infos, &VariableInfo{
+			Name:   name,
+			Type:   r.variables[name],
+			GoType: r.variableTypes[name],
+			Lazy:   lazy,
+			Doc:    r.variableDocs[name],
+		})
+	}
+	return infos
+}
+
+// Functions returns the declared functions and methods sorted by name,
+// including the operators of registered CEL values, with their overloads sorted by ID.
+func (r *Registry) Functions() ([]*FunctionInfo, error) {
+	infos := make([]*FunctionInfo, 0, len(r.funcs))
+	for _, name := range sortedKeys(r.funcs) {
+		fn, err := decls.NewFunction(name, r.funcs[name].options()...)
+		if err != nil {
+			return nil, err
+		}
+		info := &FunctionInfo{
+			Name: name,
+		}
+		for _, o := range fn.OverloadDecls() {
+			registered := r.overloads[o.ID()]
+			info.Overloads = append(info.Overloads, &OverloadInfo{
+				ID:          o.ID(),
+				Member:      o.IsMemberFunction(),
+				ArgTypes:    o.ArgTypes(),
+				ResultType:  o.ResultType(),
+				GoType:      registered.goType(),
+				Description: registered.config.description,
+				ParamNames:  registered.config.paramNames,
+				Examples:    registered.config.examples,
+				Deprecated:  registered.config.deprecated,
+				name:        name,
+			})
+		}
+		sort.Slice(info.Overloads, func(i, j int) bool {
+			return info.Overloads[i].ID < info.Overloads[j].ID
+		})
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// Conversions returns the registered conversions sorted by the Go type converted from.
+func (r *Registry) Conversions() []*ConversionInfo {
+	tp := r.nativeTypeProvider
+	infos := make([]*ConversionInfo, 0, len(tp.conversions)+len(tp.reverseConversions))
+	for from, conv := range tp.conversions {
+		infos = append(infos, &ConversionInfo{
+			From: from,
+			To:   conv.targetType,
+		})
+	}
+	for to, conv := range tp.reverseConversions {
+		infos = append(infos, &ConversionInfo{
+			From: conv.targetType,
+			To:   to,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].From.String() != infos[j].From.String() {
+			return infos[i].From.String() < infos[j].From.String()
+		}
+		return infos[i].To.String() < infos[j].To.String()
+	})
+	return infos
+}
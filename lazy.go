@@ -0,0 +1,120 @@
+package easycel
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/interpreter"
+)
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// RegisterLazyVariable registers a variable whose value is computed on demand by the resolver,
+// which must be of the form func(context.Context) (T, error).
+// The resolver is called at most once per activation created by NewLazyActivation,
+// and only if the expression references the variable.
+func (r *Registry) RegisterLazyVariable(name string, resolver any) error {
+	funVal := reflect.ValueOf(resolver)
+	if funVal.Kind() != reflect.Func {
+		return fmt.Errorf("resolver of variable %s must be func", name)
+	}
+	typ := funVal.Type()
+	if typ.NumIn() != 1 || typ.In(0) != contextType {
+		return fmt.Errorf("resolver of variable %s must accept a single context.Context", name)
+	}
+	if typ.NumOut() != 2 || !typ.Out(1).AssignableTo(errorType) {
+		return fmt.Errorf("resolver of variable %s must return a value and an error", name)
+	}
+
+	err := r.DeclareVariable(name, typ.Out(0))
+	if err != nil {
+		return err
+	}
+	r.lazyVariables[name] = funVal
+	return nil
+}
+
+// LazyVariables returns the sorted names of the lazy variables referenced by the checked AST.
+func (r *Registry) LazyVariables(ast *cel.Ast) []string {
+	if !ast.IsChecked() {
+		return nil
+	}
+	seen := map[string]struct{}{}
+	for _, ref := range ast.NativeRep().ReferenceMap() {
+		if ref.Value != nil || ref.Name == "" {
+			continue
+		}
+		if _, ok := r.lazyVariables[ref.Name]; ok {
+			seen[ref.Name] = struct{}{}
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewLazyActivation creates an activation resolving the registered lazy variables with the context,
+// all other variables are resolved from vars, which may be nil, a map or an interpreter.Activation.
+func (r *Registry) NewLazyActivation(ctx context.Context, vars any) (interpreter.Activation, error) {
+	if vars == nil {
+		vars = interpreter.EmptyActivation()
+	}
+	parent, err := interpreter.NewActivation(vars)
+	if err != nil {
+		return nil, err
+	}
+	return &lazyActivation{
+		ctx:       ctx,
+		parent:    parent,
+		resolvers: r.lazyVariables,
+	}, nil
+}
+
+var _ interpreter.Activation = (*lazyActivation)(nil)
+
+// lazyActivation resolves lazy variables on first use and memoises their values.
+type lazyActivation struct {
+	ctx       context.Context
+	parent    interpreter.Activation
+	resolvers map[string]reflect.Value
+	values    map[string]any
+}
+
+// ResolveName implements the interpreter.Activation interface method.
+func (a *lazyActivation) ResolveName(name string) (any, bool) {
+	if val, ok := a.parent.ResolveName(name); ok {
+		return val, true
+	}
+	if val, ok := a.values[name]; ok {
+		return val, true
+	}
+	resolver, ok := a.resolvers[name]
+	if !ok {
+		return nil, false
+	}
+
+	var val any
+	results := resolver.Call([]reflect.Value{reflect.ValueOf(a.ctx)})
+	if err, _ := results[1].Interface().(error); err != nil {
+		val = types.WrapErr(fmt.Errorf("resolve variable %s: %w", name, err))
+	} else {
+		val = results[0].Interface()
+	}
+	if a.values == nil {
+		a.values = map[string]any{}
+	}
+	a.values[name] = val
+	return val, true
+}
+
+// Parent implements the interpreter.Activation interface method.
+func (a *lazyActivation) Parent() interpreter.Activation {
+	return a.parent
+}
@@ -19,22 +19,30 @@ type Type interface {
 
 func newNativeTypeProvider(tagName string, adapter types.Adapter, provider types.Provider) *nativeTypeProvider {
 	return &nativeTypeProvider{
-		tagName:      tagName,
-		conversions:  make(map[reflect.Type]*convertType),
-		nativeTypes:  make(map[string]Type),
-		baseAdapter:  adapter,
-		baseProvider: provider,
+		tagName:            tagName,
+		conversions:        make(map[reflect.Type]*convertType),
+		reverseConversions: make(map[reflect.Type]*convertType),
+		nativeTypes:        make(map[string]Type),
+		typeNames:          make(map[reflect.Type]string),
+		typeValues:         make(map[reflect.Type]*types.Type),
+		baseAdapter:        adapter,
+		baseProvider:       provider,
 	}
 }
 
 var _ types.Provider = (*nativeTypeProvider)(nil)
 
 type nativeTypeProvider struct {
-	tagName      string
-	conversions  map[reflect.Type]*convertType
-	nativeTypes  map[string]Type
-	baseAdapter  types.Adapter
-	baseProvider types.Provider
+	tagName     string
+	conversions map[reflect.Type]*convertType
+	// reverseConversions are keyed by the native type they produce.
+	reverseConversions map[reflect.Type]*convertType
+	nativeTypes        map[string]Type
+	typeNames          map[reflect.Type]string
+	typeValues         map[reflect.Type]*types.Type
+	presence           FieldPresence
+	baseAdapter        types.Adapter
+	baseProvider       types.Provider
 }
 
 type convertType struct {
@@ -51,14 +59,23 @@ func (tp *nativeTypeProvider) registerConversionsFunc(fun interface{}) error {
 		return fmt.Errorf("conversion must return a single value")
 	}
 
-	if !typ.Out(0).Implements(refValType) {
-		return fmt.Errorf("conversion must return a value, must implement ref.Val")
-	}
-
 	if typ.NumIn() != 1 {
 		return fmt.Errorf("conversion must accept a single value")
 	}
 
+	// A reverse conversion turns a CEL value back into the native type.
+	if typ.In(0).Implements(refValType) && !typ.Out(0).Implements(refValType) {
+		tp.reverseConversions[typ.Out(0)] = &convertType{
+			targetType:  typ.In(0),
+			convertFunc: reflect.ValueOf(fun),
+		}
+		return nil
+	}
+
+	if !typ.Out(0).Implements(refValType) {
+		return fmt.Errorf("conversion must return a value, must implement ref.Val")
+	}
+
 	if typ.In(0).Implements(refValType) {
 		return fmt.Errorf("conversion must accept a single value, must not implement ref.Val")
 	}
@@ -70,15 +87,22 @@ func (tp *nativeTypeProvider) registerConversionsFunc(fun interface{}) error {
 	return nil
 }
 
-func (tp *nativeTypeProvider) registerNativeType(tagName string, rawType reflect.Type) (t Type, err error) {
-	typeName := rawTypeName(rawType)
+func (tp *nativeTypeProvider) registerNativeType(tagName string, typeName string, rawType reflect.Type, impls ...reflect.Type) (t Type, err error) {
+	if typeName == "" {
+		typeName = rawTypeName(rawType)
+	}
 	if _, ok := tp.nativeTypes[typeName]; ok {
 		return nil, fmt.Errorf("native type already registered: %v", typeName)
 	}
+	if name, ok := tp.typeNames[rawType]; ok {
+		return nil, fmt.Errorf("native type %v already registered as %v", rawType, name)
+	}
 
 	switch rawType.Kind() {
 	case reflect.Struct:
-		t, err = newStructType(tagName, rawType)
+		t, err = newStructType(tagName, typeName, rawType)
+	case reflect.Interface:
+		t, err = newInterfaceType(tagName, typeName, rawType, impls)
 	}
 	if err != nil {
 		return nil, err
@@ -88,22 +112,50 @@ func (tp *nativeTypeProvider) registerNativeType(tagName string, rawType reflect
 	}
 
 	tp.nativeTypes[typeName] = t
+	tp.typeNames[rawType] = typeName
+	// Cached CEL types may refer to the type by its previous name.
+	clear(tp.typeValues)
 	return t, nil
 }
 
-func (tp *nativeTypeProvider) registerType(refType any) (Type, error) {
+func (tp *nativeTypeProvider) registerType(typeName string, refType any) (Type, error) {
 	switch rt := refType.(type) {
 	case reflect.Type:
 		rawType := rt
-		return tp.registerNativeType(tp.tagName, rawType)
+		return tp.registerNativeType(tp.tagName, typeName, rawType)
 	case reflect.Value:
 		rawType := rt.Type()
-		return tp.registerNativeType(tp.tagName, rawType)
+		return tp.registerNativeType(tp.tagName, typeName, rawType)
 	default:
 		return nil, fmt.Errorf("unsupported native type: %v (%T) must be reflect.Type or reflect.Value", rt, rt)
 	}
 }
 
+func (tp *nativeTypeProvider) registerInterface(typeName string, rawType reflect.Type, impls []reflect.Type) (Type, error) {
+	if rawType.Kind() != reflect.Interface {
+		return nil, fmt.Errorf("unsupported native type: %v must be an interface", rawType)
+	}
+	return tp.registerNativeType(tp.tagName, typeName, rawType, impls...)
+}
+
+// typeName returns the CEL type name of the raw type, preferring the name
+// the type was registered with.
+func (tp *nativeTypeProvider) typeName(rawType reflect.Type) string {
+	if name, ok := tp.typeNames[rawType]; ok {
+		return name
+	}
+	return rawTypeName(rawType)
+}
+
+// nativeType returns the registered Type of the raw type, or an unregistered
+// Type named after the Go type.
+func (tp *nativeTypeProvider) nativeType(rawType reflect.Type) (Type, error) {
+	if name, ok := tp.typeNames[rawType]; ok {
+		return tp.nativeTypes[name], nil
+	}
+	return newStructType(tp.tagName, rawTypeName(rawType), rawType)
+}
+
 // EnumValue proxies to the types.Provider configured at the times the NativeTypes
 // option was configured.
 func (tp *nativeTypeProvider) EnumValue(enumName string) ref.Val {
@@ -152,6 +204,9 @@ func (tp *nativeTypeProvider) toTargetValue(rawType reflect.Type, val reflect.Va
 
 // FindStructType returns the Type give a qualified type name.
 func (tp *nativeTypeProvider) FindStructType(structType string) (*types.Type, bool) {
+	if it, ok := tp.nativeTypes[structType].(*interfaceType); ok {
+		return types.NewTypeTypeWithParam(tp.getTypeValue(it.GetRawType())), true
+	}
 	rawType, found := tp.findStructRawType(structType)
 	if !found {
 		return tp.baseProvider.FindStructType(structType)
@@ -165,12 +220,15 @@ func (tp *nativeTypeProvider) FindStructType(structType string) (*types.Type, bo
 		return tp.baseProvider.FindStructType(structType)
 	}
 
-	return types.NewTypeTypeWithParam(getTypeValue(rawType)), true
+	return types.NewTypeTypeWithParam(tp.getTypeValue(rawType)), true
 }
 
 // FindStructFieldNames returns thet field names associated with the type, if the type
 // is found.
 func (tp *nativeTypeProvider) FindStructFieldNames(structType string) ([]string, bool) {
+	if it, ok := tp.nativeTypes[structType].(*interfaceType); ok {
+		return tp.findInterfaceFieldNames(it), true
+	}
 	rawType, found := tp.findStructRawType(structType)
 	if !found {
 		return nil, false
@@ -186,6 +244,9 @@ func (tp *nativeTypeProvider) FindStructFieldNames(structType string) ([]string,
 // FindStructFieldType returns the field type for a checked type value. Returns
 // false if the field could not be found.
 func (tp *nativeTypeProvider) FindStructFieldType(structType, fieldName string) (*types.FieldType, bool) {
+	if it, ok := tp.nativeTypes[structType].(*interfaceType); ok {
+		return tp.findInterfaceFieldType(it, fieldName)
+	}
 	rawType, found := tp.findStructRawType(structType)
 	if !found {
 		return tp.baseProvider.FindStructFieldType(structType, fieldName)
@@ -210,27 +271,30 @@ func (tp *nativeTypeProvider) FindStructFieldType(structType, fieldName string)
 	}
 
 	rawType = tp.toTargetType(fieldRawType.Type)
-	typ := getTypeValue(rawType)
+	typ := tp.getTypeValue(rawType)
+
+	// Selecting a pointer field through a nil object yields null, as the field is declared nullable.
+	nullable := fieldRawType.Type.Kind() == reflect.Pointer
 
 	ft := &types.FieldType{
 		Type: typ,
 		IsSet: func(obj any) bool {
 			refVal := reflect.Indirect(reflect.ValueOf(obj))
+			if !refVal.IsValid() {
+				return false
+			}
 			refField := refVal.Field(fieldIndex)
-			return !refField.IsZero()
+			return tp.isFieldSet(fieldRawType, refField)
 		},
 	}
 
 	getFrom := func(obj any) (reflect.Value, error) {
-		if obj == nil {
-			return reflect.Value{}, fmt.Errorf("failed to get field value: object is nil")
-		}
-		rawValue := reflect.ValueOf(obj)
-		if rawValue.Kind() == reflect.Ptr {
-			if rawValue.IsNil() {
-				return reflect.Value{}, fmt.Errorf("failed to get field value: object is nil")
+		rawValue := reflect.Indirect(reflect.ValueOf(obj))
+		if !rawValue.IsValid() {
+			if nullable {
+				return reflect.Value{}, nil
 			}
-			rawValue = rawValue.Elem()
+			return reflect.Value{}, fmt.Errorf("failed to get field value %q: object is nil", fieldName)
 		}
 
 		refField := rawValue.Field(fieldIndex)
@@ -242,6 +306,9 @@ func (tp *nativeTypeProvider) FindStructFieldType(structType, fieldName string)
 			if err != nil {
 				return nil, err
 			}
+			if !refField.IsValid() {
+				return nil, nil
+			}
 			return refField.Interface(), nil
 		}
 	} else {
@@ -250,6 +317,9 @@ func (tp *nativeTypeProvider) FindStructFieldType(structType, fieldName string)
 			if err != nil {
 				return nil, err
 			}
+			if !refField.IsValid() {
+				return nil, nil
+			}
 
 			data, ok := tp.toTargetValue(fieldRawType.Type, refField)
 			if !ok {
@@ -262,12 +332,84 @@ func (tp *nativeTypeProvider) FindStructFieldType(structType, fieldName string)
 	return ft, true
 }
 
+// findInterfaceFieldNames returns the fields shared with the same CEL type by
+// all implementations of the interface.
+func (tp *nativeTypeProvider) findInterfaceFieldNames(it *interfaceType) []string {
+	impls := it.Implementations()
+	if len(impls) == 0 {
+		return nil
+	}
+	var names []string
+	for _, name := range getStructFields(impls[0], tp.tagName) {
+		if _, ok := tp.findInterfaceFieldType(it, name); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// findInterfaceFieldType returns the field type of a field shared by all
+// implementations of the interface, dispatching on the concrete type at runtime.
+func (tp *nativeTypeProvider) findInterfaceFieldType(it *interfaceType, fieldName string) (*types.FieldType, bool) {
+	impls := it.Implementations()
+	if len(impls) == 0 {
+		return nil, false
+	}
+
+	var typ *types.Type
+	fieldTypes := make(map[reflect.Type]*types.FieldType, len(impls))
+	for _, impl := range impls {
+		ft, found := tp.FindStructFieldType(tp.typeName(impl), fieldName)
+		if !found {
+			return nil, false
+		}
+		if typ == nil {
+			typ = ft.Type
+		} else if !typ.IsExactType(ft.Type) {
+			return nil, false
+		}
+		fieldTypes[impl] = ft
+	}
+
+	lookup := func(obj any) (*types.FieldType, error) {
+		refVal := reflect.Indirect(reflect.ValueOf(obj))
+		if !refVal.IsValid() {
+			return nil, fmt.Errorf("failed to get field value: object is nil")
+		}
+		ft, ok := fieldTypes[refVal.Type()]
+		if !ok {
+			return nil, fmt.Errorf("type %v is not a known implementation of %v", refVal.Type(), it.TypeName())
+		}
+		return ft, nil
+	}
+	return &types.FieldType{
+		Type: typ,
+		IsSet: func(obj any) bool {
+			ft, err := lookup(obj)
+			if err != nil {
+				return false
+			}
+			return ft.IsSet(obj)
+		},
+		GetFrom: func(obj any) (any, error) {
+			ft, err := lookup(obj)
+			if err != nil {
+				return nil, err
+			}
+			return ft.GetFrom(obj)
+		},
+	}, true
+}
+
 // NewValue implements the types.Provider interface method.
 func (tp *nativeTypeProvider) NewValue(typeName string, fields map[string]ref.Val) ref.Val {
 	t, found := tp.nativeTypes[typeName]
 	if !found {
 		return tp.baseProvider.NewValue(typeName, fields)
 	}
+	if _, ok := t.(*interfaceType); ok {
+		return types.NewErr("cannot create value of interface type: %s", typeName)
+	}
 	refPtr := reflect.New(t.GetRawType())
 	refVal := refPtr.Elem()
 
@@ -286,7 +428,7 @@ func (tp *nativeTypeProvider) NewValue(typeName string, fields map[string]ref.Va
 		if !refFieldDef.IsExported() || !isSupportedType(refFieldDef.Type) {
 			return types.NewErr("no such field: %s", fieldName)
 		}
-		fieldVal, err := val.ConvertToNative(refFieldDef.Type)
+		fieldVal, err := convertToNative(val, refFieldDef.Type)
 		if err != nil {
 			return types.NewErr(err.Error())
 		}
@@ -321,6 +463,11 @@ func (tp *nativeTypeProvider) NativeToValue(val any) ref.Val {
 		case []byte:
 			return tp.baseAdapter.NativeToValue(val)
 		default:
+			if rawVal.Kind() == reflect.Array && rawVal.Type().Elem() == byteType {
+				b := make([]byte, rawVal.Len())
+				reflect.Copy(reflect.ValueOf(b), rawVal)
+				return types.Bytes(b)
+			}
 			return newListObject(tp, val)
 		}
 	case reflect.Map:
@@ -330,7 +477,7 @@ func (tp *nativeTypeProvider) NativeToValue(val any) ref.Val {
 		case time.Time:
 			return tp.baseAdapter.NativeToValue(val)
 		default:
-			return newStructObject(tp, tp.tagName, val, rawVal)
+			return newStructObject(tp, val, rawVal)
 		}
 	case reflect.Pointer:
 		if rawVal.IsNil() {
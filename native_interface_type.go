@@ -0,0 +1,88 @@
+package easycel
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+func newInterfaceType(tagName string, typeName string, refType reflect.Type, impls []reflect.Type) (Type, error) {
+	if refType.Kind() != reflect.Interface {
+		return nil, fmt.Errorf("unsupported reflect.Type %v, must be reflect.Interface", refType)
+	}
+	for _, impl := range impls {
+		if !impl.Implements(refType) && !reflect.PointerTo(impl).Implements(refType) {
+			return nil, fmt.Errorf("type %v does not implement %v", impl, refType)
+		}
+	}
+	return &interfaceType{
+		tagName:  tagName,
+		typeName: typeName,
+		refType:  refType,
+		impls:    impls,
+	}, nil
+}
+
+type interfaceType struct {
+	tagName  string
+	typeName string
+	refType  reflect.Type
+	impls    []reflect.Type
+}
+
+// HasTrait implements the ref.Type interface method.
+func (t *interfaceType) HasTrait(trait int) bool {
+	return structTypeTraitMask&trait == trait
+}
+
+// TypeName implements the ref.Type interface method.
+func (t *interfaceType) TypeName() string {
+	return t.typeName
+}
+
+// ConvertToNative implements ref.Val.ConvertToNative.
+func (t *interfaceType) ConvertToNative(typeDesc reflect.Type) (any, error) {
+	return nil, fmt.Errorf("type conversion error for type to '%v'", typeDesc)
+}
+
+// ConvertToType implements ref.Val.ConvertToType.
+func (t *interfaceType) ConvertToType(typeVal ref.Type) ref.Val {
+	switch typeVal {
+	case types.TypeType:
+		return t
+	}
+	return types.NewErr("type conversion error from '%s' to '%s'", types.TypeType, typeVal)
+}
+
+// Equal returns true of both type names are equal to each other.
+func (t *interfaceType) Equal(other ref.Val) ref.Val {
+	otherType, ok := other.(ref.Type)
+	return types.Bool(ok && t.TypeName() == otherType.TypeName())
+}
+
+// Type implements the ref.Val interface method.
+func (t *interfaceType) Type() ref.Type {
+	return types.TypeType
+}
+
+// Value implements the ref.Val interface method.
+func (t *interfaceType) Value() any {
+	return t.typeName
+}
+
+// TagName implements the interfaceType interface method.
+func (t *interfaceType) TagName() string {
+	return t.tagName
+}
+
+// GetRawType returns the underlying reflect.Type.
+func (t *interfaceType) GetRawType() reflect.Type {
+	return t.refType
+}
+
+// Implementations returns the concrete struct types known to implement the interface.
+func (t *interfaceType) Implementations() []reflect.Type {
+	return t.impls
+}
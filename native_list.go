@@ -1,16 +1,33 @@
 package easycel
 
 import (
+	"reflect"
+
 	"github.com/google/cel-go/common/types"
 	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
 )
 
 func newListObject(adapter types.Adapter, value any) ref.Val {
 	switch v := value.(type) {
 	case []string:
-		return types.NewStringList(adapter, v)
+		return &listObject{types.NewStringList(adapter, v)}
 	case []ref.Val:
-		return types.NewDynamicList(adapter, v)
+		return &listObject{types.NewDynamicList(adapter, v)}
+	}
+	return &listObject{types.NewDynamicList(adapter, value)}
+}
+
+// listObject converts lists to fixed-size arrays only when their length matches,
+// as the CEL lists don't validate the length.
+type listObject struct {
+	traits.Lister
+}
+
+// ConvertToNative implements the ref.Val interface method.
+func (l *listObject) ConvertToNative(typeDesc reflect.Type) (any, error) {
+	if typeDesc.Kind() == reflect.Array {
+		return convertToNative(l.Lister, typeDesc)
 	}
-	return types.NewDynamicList(adapter, value)
+	return l.Lister.ConvertToNative(typeDesc)
 }
@@ -8,13 +8,14 @@ import (
 	"github.com/google/cel-go/common/types/ref"
 )
 
-func newStructObject(adapter types.Adapter, tagName string, val any, refValue reflect.Value) ref.Val {
-	valType, err := newStructType(tagName, refValue.Type())
+func newStructObject(tp *nativeTypeProvider, val any, refValue reflect.Value) ref.Val {
+	valType, err := tp.nativeType(refValue.Type())
 	if err != nil {
 		return types.WrapErr(err)
 	}
 	return &structObject{
-		Adapter:  adapter,
+		Adapter:  tp,
+		tp:       tp,
 		val:      val,
 		valType:  valType,
 		refValue: refValue,
@@ -23,6 +24,7 @@ func newStructObject(adapter types.Adapter, tagName string, val any, refValue re
 
 type structObject struct {
 	types.Adapter
+	tp       *nativeTypeProvider
 	val      any
 	valType  Type
 	refValue reflect.Value
@@ -41,11 +43,63 @@ func (o *structObject) ConvertToNative(typeDesc reflect.Type) (any, error) {
 		ptr.Elem().Set(o.refValue)
 		return ptr.Interface(), nil
 	}
+	if typeDesc.Kind() == reflect.Map && typeDesc.Key().Kind() == reflect.String {
+		return o.convertToMap(typeDesc)
+	}
+	if typeDesc.Kind() == reflect.Interface {
+		if o.refValue.Type().Implements(typeDesc) {
+			return o.val, nil
+		}
+		if reflect.PointerTo(o.refValue.Type()).Implements(typeDesc) {
+			ptr := reflect.New(o.refValue.Type())
+			ptr.Elem().Set(o.refValue)
+			return ptr.Interface(), nil
+		}
+	}
 	return nil, fmt.Errorf("type conversion error from '%v' to '%v'", o.Type(), typeDesc)
 }
 
+// convertToMap converts the struct to a map keyed by field name, leaving out unset fields.
+func (o *structObject) convertToMap(typeDesc reflect.Type) (any, error) {
+	refValue := reflect.Indirect(o.refValue)
+	refType := refValue.Type()
+	fieldMap := getStructFieldMap(refType, o.tp.tagName)
+
+	out := reflect.MakeMapWithSize(typeDesc, len(fieldMap))
+	for _, name := range getStructFields(refType, o.tp.tagName) {
+		fieldIndex := fieldMap[name]
+		fieldDef := refType.Field(fieldIndex)
+		refField := refValue.Field(fieldIndex)
+		if !isSupportedType(fieldDef.Type) || !o.tp.isFieldSet(fieldDef, refField) {
+			continue
+		}
+
+		val := o.tp.NativeToValue(refField.Interface())
+		if types.IsError(val) {
+			return nil, val.(*types.Err)
+		}
+		var elem any = val
+		if typeDesc.Elem() != refValType {
+			var err error
+			elem, err = val.ConvertToNative(typeDesc.Elem())
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", name, err)
+			}
+		}
+		refElem := reflect.ValueOf(elem)
+		if !refElem.IsValid() {
+			refElem = reflect.Zero(typeDesc.Elem())
+		}
+		out.SetMapIndex(reflect.ValueOf(name).Convert(typeDesc.Key()), refElem)
+	}
+	return out.Interface(), nil
+}
+
 // ConvertToType implements the ref.Val interface method.
 func (o *structObject) ConvertToType(typeVal ref.Type) ref.Val {
+	if typeVal == types.TypeType {
+		return o.valType
+	}
 	if typeVal.TypeName() == o.valType.TypeName() {
 		return o
 	}
@@ -72,6 +126,40 @@ func (o *structObject) Equal(other ref.Val) ref.Val {
 	return types.Bool(reflect.DeepEqual(val, otherVal))
 }
 
+// Get implements the traits.Indexer interface method.
+func (o *structObject) Get(index ref.Val) ref.Val {
+	_, refField, err := o.field(index)
+	if err != nil {
+		return types.WrapErr(err)
+	}
+	if out, ok := o.tp.toTargetValue(refField.Type(), refField); ok {
+		return out[0].Interface().(ref.Val)
+	}
+	return o.tp.NativeToValue(refField.Interface())
+}
+
+// IsSet implements the traits.FieldTester interface method.
+func (o *structObject) IsSet(field ref.Val) ref.Val {
+	fieldDef, refField, err := o.field(field)
+	if err != nil {
+		return types.WrapErr(err)
+	}
+	return types.Bool(o.tp.isFieldSet(fieldDef, refField))
+}
+
+func (o *structObject) field(index ref.Val) (reflect.StructField, reflect.Value, error) {
+	name, ok := index.(types.String)
+	if !ok {
+		return reflect.StructField{}, reflect.Value{}, fmt.Errorf("no such overload: field name must be a string, got %v", index.Type())
+	}
+	refValue := reflect.Indirect(o.refValue)
+	fieldIndex, ok := getStructFieldMap(refValue.Type(), o.tp.tagName)[string(name)]
+	if !ok {
+		return reflect.StructField{}, reflect.Value{}, fmt.Errorf("no such field: %s", name)
+	}
+	return refValue.Type().Field(fieldIndex), refValue.Field(fieldIndex), nil
+}
+
 // Type implements the ref.Val interface method.
 func (o *structObject) Type() ref.Type {
 	return o.valType
@@ -13,13 +13,13 @@ var (
 	structTypeTraitMask = traits.FieldTesterType | traits.IndexerType
 )
 
-func newStructType(tagName string, refType reflect.Type) (Type, error) {
+func newStructType(tagName string, typeName string, refType reflect.Type) (Type, error) {
 	if refType.Kind() != reflect.Struct {
 		return nil, fmt.Errorf("unsupported reflect.Type %v, must be reflect.Struct", refType)
 	}
 	return &structType{
 		tagName:  tagName,
-		typeName: rawTypeName(refType),
+		typeName: typeName,
 		refType:  refType,
 	}, nil
 }
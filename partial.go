@@ -0,0 +1,88 @@
+package easycel
+
+import (
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+// PartialResult is the result of a partial evaluation, either a final value
+// or a residual expression which only depends on the unknown attributes.
+type PartialResult struct {
+	// Value is the final value, nil if the evaluation depends on unknown attributes.
+	Value ref.Val
+	// Residual is the checked residual AST, nil if the evaluation is complete.
+	Residual *cel.Ast
+	// ResidualSource is the residual expression, which can be stored and evaluated later.
+	ResidualSource string
+}
+
+// Complete returns true if the evaluation produced a final value.
+func (r *PartialResult) Complete() bool {
+	return r.Residual == nil
+}
+
+// PartialEval evaluates the source with the given attributes marked as unknown,
+// either top-level variables like "msg" or field paths like "msg.meta.name",
+// where "*" matches any field or index.
+// The vars may be nil, a map or an interpreter.Activation.
+func (e *Environment) PartialEval(src string, vars any, unknowns ...string) (*PartialResult, error) {
+	ast, err := e.Compile(src)
+	if err != nil {
+		return nil, err
+	}
+	program, err := e.env.Program(ast, cel.EvalOptions(cel.OptTrackState, cel.OptPartialEval))
+	if err != nil {
+		return nil, err
+	}
+
+	if vars == nil {
+		vars = map[string]any{}
+	}
+	patterns := make([]*cel.AttributePatternType, 0, len(unknowns))
+	for _, unknown := range unknowns {
+		patterns = append(patterns, attributePattern(unknown))
+	}
+	activation, err := cel.PartialVars(vars, patterns...)
+	if err != nil {
+		return nil, err
+	}
+
+	out, details, err := program.Eval(activation)
+	if err != nil {
+		return nil, err
+	}
+	if !types.IsUnknown(out) {
+		return &PartialResult{
+			Value: out,
+		}, nil
+	}
+
+	residual, err := e.env.ResidualAst(ast, details)
+	if err != nil {
+		return nil, err
+	}
+	source, err := cel.AstToString(residual)
+	if err != nil {
+		return nil, err
+	}
+	return &PartialResult{
+		Residual:       residual,
+		ResidualSource: source,
+	}, nil
+}
+
+func attributePattern(path string) *cel.AttributePatternType {
+	names := strings.Split(path, ".")
+	pattern := cel.AttributePattern(names[0])
+	for _, name := range names[1:] {
+		if name == "*" {
+			pattern = pattern.Wildcard()
+		} else {
+			pattern = pattern.QualString(name)
+		}
+	}
+	return pattern
+}
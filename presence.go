@@ -0,0 +1,53 @@
+package easycel
+
+import (
+	"reflect"
+)
+
+// FieldPresence selects how the presence of non-pointer struct fields is determined,
+// pointer fields are always present when they are non-nil.
+type FieldPresence int
+
+const (
+	// FieldPresenceZeroUnset treats fields holding the zero value as unset, like proto3 scalars.
+	FieldPresenceZeroUnset FieldPresence = iota
+	// FieldPresenceAlwaysSet treats fields as always set.
+	FieldPresenceAlwaysSet
+	// FieldPresenceOmitEmpty treats fields tagged with omitempty as unset when they are empty,
+	// and all other fields as always set.
+	FieldPresenceOmitEmpty
+)
+
+// isFieldSet reports whether the field holding the value is set according to the presence semantics.
+func (tp *nativeTypeProvider) isFieldSet(field reflect.StructField, value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return !value.IsNil()
+	}
+
+	switch tp.presence {
+	case FieldPresenceAlwaysSet:
+		return true
+	case FieldPresenceOmitEmpty:
+		if !fieldTagHasOption(field, tp.tagName, "omitempty") {
+			return true
+		}
+		return !isEmptyValue(value)
+	default:
+		return !value.IsZero()
+	}
+}
+
+// isEmptyValue follows the encoding/json definition of empty values.
+func isEmptyValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return value.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return value.IsZero()
+	}
+	return false
+}
@@ -6,11 +6,13 @@ import (
 	"strings"
 
 	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker"
 	"github.com/google/cel-go/common/operators"
 	"github.com/google/cel-go/common/overloads"
 	"github.com/google/cel-go/common/types"
 	"github.com/google/cel-go/common/types/ref"
 	"github.com/google/cel-go/common/types/traits"
+	"github.com/google/cel-go/interpreter"
 )
 
 var (
@@ -20,12 +22,23 @@ var (
 
 type Registry struct {
 	nativeTypeProvider *nativeTypeProvider
-	funcs              map[string][]cel.FunctionOpt
+	funcs              map[string]*function
+	overloads          map[string]*overload
 	variables          map[string]*cel.Type
+	variableTypes      map[string]reflect.Type
+	variableDocs       map[string]string
+	inheritedVariables map[string]struct{}
+	refTypes           []ref.Type
 	registry           *types.Registry
 	adapter            types.Adapter
 	provider           types.Provider
 	tagName            string
+	container          string
+	presence           FieldPresence
+	inputType          reflect.Type
+	inputFields        map[string]int
+	lazyVariables      map[string]reflect.Value
+	costLimit          uint64
 	libraryName        string
 }
 
@@ -52,29 +65,64 @@ func WithTagName(tagName string) RegistryOption {
 	}
 }
 
+// WithContainer sets the container used to resolve unqualified type names,
+// e.g. with the container "acme.v1" the type "acme.v1.Message" can be
+// referenced as "Message".
+func WithContainer(container string) RegistryOption {
+	return func(r *Registry) {
+		r.container = container
+	}
+}
+
+// WithFieldPresence sets how has() and optional field selection determine
+// whether non-pointer struct fields are set, the default is FieldPresenceZeroUnset.
+func WithFieldPresence(presence FieldPresence) RegistryOption {
+	return func(r *Registry) {
+		r.presence = presence
+	}
+}
+
+// WithCostLimit sets the default runtime cost limit of every program created with the registry,
+// evaluation fails once the limit is exceeded.
+func WithCostLimit(limit uint64) RegistryOption {
+	return func(r *Registry) {
+		r.costLimit = limit
+	}
+}
+
 // NewRegistry creates adapter new Registry.
 func NewRegistry(libraryName string, opts ...RegistryOption) *Registry {
 	r := &Registry{
-		funcs:       make(map[string][]cel.FunctionOpt),
-		variables:   make(map[string]*cel.Type),
-		tagName:     "easycel",
-		libraryName: libraryName,
+		funcs:              make(map[string]*function),
+		overloads:          make(map[string]*overload),
+		variables:          make(map[string]*cel.Type),
+		variableTypes:      make(map[string]reflect.Type),
+		variableDocs:       make(map[string]string),
+		inheritedVariables: make(map[string]struct{}),
+		lazyVariables:      make(map[string]reflect.Value),
+		tagName:            "easycel",
+		libraryName:        libraryName,
 	}
 	for _, opt := range opts {
 		opt(r)
 	}
 	registry, _ := types.NewRegistry()
-	tp := newNativeTypeProvider(r.tagName, registry, registry)
-	if r.adapter == nil {
+	r.setTypeProvider(newNativeTypeProvider(r.tagName, registry, registry), registry)
+	return r
+}
+
+// setTypeProvider sets the native type provider and the registry of CEL types,
+// which are the adapter and provider unless custom ones are set.
+func (r *Registry) setTypeProvider(tp *nativeTypeProvider, registry *types.Registry) {
+	tp.presence = r.presence
+	if r.adapter == nil || r.adapter == r.nativeTypeProvider {
 		r.adapter = tp
 	}
-	if r.provider == nil {
+	if r.provider == nil || r.provider == r.nativeTypeProvider {
 		r.provider = tp
 	}
 	r.registry = registry
 	r.nativeTypeProvider = tp
-
-	return r
 }
 
 // LibraryName implements the Library interface method.
@@ -82,19 +130,31 @@ func (r *Registry) LibraryName() string {
 	return r.libraryName
 }
 
-// CompileOptions implements the Library interface method.
+// CompileOptions implements the Library interface method,
+// functions and variables are declared sorted by name.
 func (r *Registry) CompileOptions() []cel.EnvOption {
 	opts := []cel.EnvOption{}
-	for name, fn := range r.funcs {
-		opts = append(opts, cel.Function(name, fn...))
+	for _, name := range sortedKeys(r.funcs) {
+		opts = append(opts, cel.Function(name, r.funcs[name].options()...))
 	}
-	for name, typ := range r.variables {
-		opts = append(opts, cel.Variable(name, typ))
+	for _, name := range sortedKeys(r.variables) {
+		opts = append(opts, cel.Variable(name, r.variables[name]))
 	}
 	opts = append(opts,
+		cel.OptionalTypes(),
 		cel.CustomTypeAdapter(r),
 		cel.CustomTypeProvider(r),
 	)
+	if r.container != "" {
+		opts = append(opts, cel.Container(r.container))
+	}
+	if costs := r.costOverloads(); len(costs) != 0 {
+		costOpts := make([]checker.CostOption, 0, len(costs))
+		for _, o := range costs {
+			costOpts = append(costOpts, checker.OverloadCostEstimate(o.id, o.config.cost.estimate))
+		}
+		opts = append(opts, cel.CostEstimatorOptions(costOpts...))
+	}
 	return opts
 }
 
@@ -137,7 +197,18 @@ func (r *Registry) NewValue(typeName string, fields map[string]ref.Val) ref.Val
 
 // ProgramOptions implements the Library interface method.
 func (r *Registry) ProgramOptions() []cel.ProgramOption {
-	return []cel.ProgramOption{}
+	opts := []cel.ProgramOption{}
+	if costs := r.costOverloads(); len(costs) != 0 {
+		costOpts := make([]interpreter.CostTrackerOption, 0, len(costs))
+		for _, o := range costs {
+			costOpts = append(costOpts, interpreter.OverloadCostTracker(o.id, o.config.cost.track))
+		}
+		opts = append(opts, cel.CostTrackerOptions(costOpts...))
+	}
+	if r.costLimit != 0 {
+		opts = append(opts, cel.CostLimit(r.costLimit))
+	}
+	return opts
 }
 
 // RegisterType registers adapter type with the registry.
@@ -148,40 +219,164 @@ func (r *Registry) RegisterType(refTypes any) error {
 		if err != nil {
 			return err
 		}
-		return r.registry.RegisterType(v.Type())
+		return r.registerRefType(v.Type())
 	case ref.Type:
-		return r.registry.RegisterType(v)
+		return r.registerRefType(v)
 	default:
-		_, err := r.nativeTypeProvider.registerType(reflect.TypeOf(refTypes))
+		_, err := r.nativeTypeProvider.registerType("", reflect.TypeOf(refTypes))
+		return err
+	}
+}
+
+func (r *Registry) registerRefType(t ref.Type) error {
+	err := r.registry.RegisterType(t)
+	if err != nil {
 		return err
 	}
+	r.refTypes = append(r.refTypes, t)
+	return nil
+}
+
+// RegisterTypeAs registers a native type with the registry under the given CEL type name,
+// e.g. "acme.v1.Message", instead of the name derived from the Go package.
+// The type must be registered before the variables and functions using it,
+// which are declared with the name derived from the Go package otherwise.
+func (r *Registry) RegisterTypeAs(typeName string, refTypes any) error {
+	if typeName == "" {
+		return fmt.Errorf("type name is required")
+	}
+	switch refTypes.(type) {
+	case ref.Val, ref.Type:
+		return fmt.Errorf("type %T already has a CEL type name", refTypes)
+	}
+	rawType := reflect.TypeOf(refTypes)
+	if rawType == nil {
+		return fmt.Errorf("type %s must not be nil", typeName)
+	}
+	if _, ok := r.nativeTypeProvider.typeNames[rawType]; !ok {
+		err := r.checkTypeUndeclared(rawTypeName(rawType))
+		if err != nil {
+			return fmt.Errorf("type %v can't be registered as %s: %w", rawType, typeName, err)
+		}
+	}
+	_, err := r.nativeTypeProvider.registerType(typeName, rawType)
+	return err
+}
+
+// RegisterInterface registers an interface type with the registry together with its known implementations,
+// e.g. RegisterInterface((*Shape)(nil), Circle{}, Square{}).
+// Fields shared by all implementations can be selected on values of the interface type,
+// the implementations are registered as well if they are not yet.
+func (r *Registry) RegisterInterface(iface any, impls ...any) error {
+	ifaceType := reflect.TypeOf(iface)
+	if ifaceType == nil || ifaceType.Kind() != reflect.Pointer || ifaceType.Elem().Kind() != reflect.Interface {
+		return fmt.Errorf("interface must be a nil pointer to an interface, e.g. (*Shape)(nil)")
+	}
+	ifaceType = ifaceType.Elem()
+
+	implTypes := make([]reflect.Type, 0, len(impls))
+	for _, impl := range impls {
+		implType := reflect.TypeOf(impl)
+		if implType == nil {
+			return fmt.Errorf("implementation of %v must not be nil", ifaceType)
+		}
+		if implType.Kind() == reflect.Pointer {
+			implType = implType.Elem()
+		}
+		if _, ok := r.nativeTypeProvider.typeNames[implType]; !ok {
+			_, err := r.nativeTypeProvider.registerType("", implType)
+			if err != nil {
+				return err
+			}
+		}
+		implTypes = append(implTypes, implType)
+	}
+	_, err := r.nativeTypeProvider.registerInterface("", ifaceType, implTypes)
+	return err
 }
 
 // RegisterVariable registers adapter value with the registry.
 func (r *Registry) RegisterVariable(name string, val interface{}) error {
-	if _, ok := r.variables[name]; ok {
-		return fmt.Errorf("variable %s already registered", name)
+	typ, err := variableValueType(name, val)
+	if err != nil {
+		return err
 	}
+	return r.DeclareVariable(name, typ)
+}
+
+// variableValueType returns the Go type of the value of the variable.
+func variableValueType(name string, val interface{}) (reflect.Type, error) {
 	typ := reflect.TypeOf(val)
-	celType, ok := convertToCelType(typ)
+	if typ == nil {
+		return nil, fmt.Errorf("variable %s value is nil, declare it by type instead", name)
+	}
+	return typ, nil
+}
+
+// DeclareVariable registers a variable of the given type with the registry,
+// interface types other than registered interfaces are declared as dyn.
+func (r *Registry) DeclareVariable(name string, typ reflect.Type) error {
+	celType, err := r.variableCelType(name, typ)
+	if err != nil {
+		return err
+	}
+	err = r.DeclareVariableType(name, celType)
+	if err != nil {
+		return err
+	}
+	r.variableTypes[name] = typ
+	return nil
+}
+
+// variableCelType returns the CEL type the variable of the Go type is declared with.
+func (r *Registry) variableCelType(name string, typ reflect.Type) (*cel.Type, error) {
+	if typ == nil {
+		return nil, fmt.Errorf("variable %s type is required", name)
+	}
+	celType, ok := r.nativeTypeProvider.convertToCelType(typ)
 	if !ok {
-		return fmt.Errorf("variable %s type %s not supported", name, typ.String())
+		return nil, fmt.Errorf("variable %s type %s not supported", name, typ.String())
+	}
+	return celType, nil
+}
+
+// DeclareVariableType registers a variable of the given CEL type with the registry,
+// e.g. cel.DynType or an abstract type created by cel.OpaqueType.
+func (r *Registry) DeclareVariableType(name string, celType *cel.Type) error {
+	if _, ok := r.variables[name]; ok {
+		if _, inherited := r.inheritedVariables[name]; !inherited {
+			return fmt.Errorf("variable %s already registered", name)
+		}
+	}
+	if celType == nil {
+		return fmt.Errorf("variable %s type is required", name)
 	}
 	r.variables[name] = celType
+	delete(r.inheritedVariables, name)
+	delete(r.variableTypes, name)
+	delete(r.variableDocs, name)
+	delete(r.lazyVariables, name)
 	return nil
 }
 
+// DeclareVariableOf registers a variable of the type T with the registry.
+func DeclareVariableOf[T any](r *Registry, name string) error {
+	return r.DeclareVariable(name, reflect.TypeOf((*T)(nil)).Elem())
+}
+
 // RegisterFunction registers adapter function with the registry.
-func (r *Registry) RegisterFunction(name string, fun interface{}) error {
-	return r.registerFunction(name, fun, false)
+func (r *Registry) RegisterFunction(name string, fun interface{}, opts ...FunctionOption) error {
+	return r.registerFunction(name, fun, false, false, opts)
 }
 
 // RegisterMethod registers adapter method with the registry.
-func (r *Registry) RegisterMethod(name string, fun interface{}) error {
-	return r.registerFunction(name, fun, true)
+func (r *Registry) RegisterMethod(name string, fun interface{}, opts ...FunctionOption) error {
+	return r.registerFunction(name, fun, true, false, opts)
 }
 
-// RegisterConversion registers adapter conversion function with the registry.
+// RegisterConversion registers adapter conversion function with the registry,
+// either from a native type to a CEL value, e.g. func(Timestamp) types.Timestamp,
+// or the reverse from a CEL value to a native type used by Decode, e.g. func(types.Timestamp) Timestamp.
 func (r *Registry) RegisterConversion(fun any) error {
 	return r.nativeTypeProvider.registerConversionsFunc(fun)
 }
@@ -189,7 +384,7 @@ func (r *Registry) RegisterConversion(fun any) error {
 func (r *Registry) registerTraits(v ref.Val) error {
 	typ := v.Type()
 
-	tv := getTypeValue(reflect.TypeOf(v))
+	tv := r.nativeTypeProvider.getTypeValue(reflect.TypeOf(v))
 
 	if _, ok := v.(traits.Adder); ok && typ.HasTrait(traits.AdderType) {
 		argsCelType := []*cel.Type{
@@ -201,7 +396,10 @@ func (r *Registry) registerTraits(v ref.Val) error {
 
 		overloadID := getOverloadID(funcName, argsCelType, resultType, false)
 		funcOpt := cel.Overload(overloadID, argsCelType, resultType, cel.OverloadOperandTrait(traits.AdderType))
-		r.funcs[funcName] = append(r.funcs[funcName], funcOpt)
+		err := r.addOverload(funcName, &overload{id: overloadID, opt: funcOpt})
+		if err != nil {
+			return err
+		}
 	}
 
 	if _, ok := v.(traits.Subtractor); ok && typ.HasTrait(traits.SubtractorType) {
@@ -214,7 +412,10 @@ func (r *Registry) registerTraits(v ref.Val) error {
 
 		overloadID := getOverloadID(funcName, argsCelType, resultType, false)
 		funcOpt := cel.Overload(overloadID, argsCelType, resultType, cel.OverloadOperandTrait(traits.SubtractorType))
-		r.funcs[funcName] = append(r.funcs[funcName], funcOpt)
+		err := r.addOverload(funcName, &overload{id: overloadID, opt: funcOpt})
+		if err != nil {
+			return err
+		}
 	}
 
 	if _, ok := v.(traits.Negater); ok && typ.HasTrait(traits.NegatorType) {
@@ -226,7 +427,10 @@ func (r *Registry) registerTraits(v ref.Val) error {
 
 		overloadID := getOverloadID(funcName, argsCelType, resultType, false)
 		funcOpt := cel.Overload(overloadID, argsCelType, resultType, cel.OverloadOperandTrait(traits.NegatorType))
-		r.funcs[funcName] = append(r.funcs[funcName], funcOpt)
+		err := r.addOverload(funcName, &overload{id: overloadID, opt: funcOpt})
+		if err != nil {
+			return err
+		}
 	}
 
 	if _, ok := v.(traits.Multiplier); ok && typ.HasTrait(traits.MultiplierType) {
@@ -239,7 +443,10 @@ func (r *Registry) registerTraits(v ref.Val) error {
 
 		overloadID := getOverloadID(funcName, argsCelType, resultType, false)
 		funcOpt := cel.Overload(overloadID, argsCelType, resultType, cel.OverloadOperandTrait(traits.MultiplierType))
-		r.funcs[funcName] = append(r.funcs[funcName], funcOpt)
+		err := r.addOverload(funcName, &overload{id: overloadID, opt: funcOpt})
+		if err != nil {
+			return err
+		}
 	}
 
 	if _, ok := v.(traits.Divider); ok && typ.HasTrait(traits.DividerType) {
@@ -252,7 +459,10 @@ func (r *Registry) registerTraits(v ref.Val) error {
 
 		overloadID := getOverloadID(funcName, argsCelType, resultType, false)
 		funcOpt := cel.Overload(overloadID, argsCelType, resultType, cel.OverloadOperandTrait(traits.DividerType))
-		r.funcs[funcName] = append(r.funcs[funcName], funcOpt)
+		err := r.addOverload(funcName, &overload{id: overloadID, opt: funcOpt})
+		if err != nil {
+			return err
+		}
 	}
 
 	if _, ok := v.(traits.Modder); ok && typ.HasTrait(traits.ModderType) {
@@ -265,7 +475,10 @@ func (r *Registry) registerTraits(v ref.Val) error {
 
 		overloadID := getOverloadID(funcName, argsCelType, resultType, false)
 		funcOpt := cel.Overload(overloadID, argsCelType, resultType, cel.OverloadOperandTrait(traits.ModderType))
-		r.funcs[funcName] = append(r.funcs[funcName], funcOpt)
+		err := r.addOverload(funcName, &overload{id: overloadID, opt: funcOpt})
+		if err != nil {
+			return err
+		}
 	}
 
 	if _, ok := v.(traits.Comparer); ok && typ.HasTrait(traits.ComparerType) {
@@ -285,7 +498,10 @@ func (r *Registry) registerTraits(v ref.Val) error {
 			funcName := comparer
 			overloadID := getOverloadID(funcName, argsCelType, resultType, false)
 			funcOpt := cel.Overload(overloadID, argsCelType, resultType, cel.OverloadOperandTrait(traits.ComparerType))
-			r.funcs[funcName] = append(r.funcs[funcName], funcOpt)
+			err := r.addOverload(funcName, &overload{id: overloadID, opt: funcOpt})
+			if err != nil {
+				return err
+			}
 		}
 	}
 
@@ -299,7 +515,10 @@ func (r *Registry) registerTraits(v ref.Val) error {
 		funcName := operators.Index
 		overloadID := getOverloadID(funcName, argsCelType, resultType, false)
 		funcOpt := cel.Overload(overloadID, argsCelType, resultType, cel.OverloadOperandTrait(traits.IndexerType))
-		r.funcs[funcName] = append(r.funcs[funcName], funcOpt)
+		err := r.addOverload(funcName, &overload{id: overloadID, opt: funcOpt})
+		if err != nil {
+			return err
+		}
 	}
 
 	if _, ok := v.(traits.Sizer); ok && typ.HasTrait(traits.SizerType) {
@@ -311,7 +530,10 @@ func (r *Registry) registerTraits(v ref.Val) error {
 		funcName := overloads.Size
 		overloadID := getOverloadID(funcName, argsCelType, resultType, false)
 		funcOpt := cel.Overload(overloadID, argsCelType, resultType, cel.OverloadOperandTrait(traits.SizerType))
-		r.funcs[funcName] = append(r.funcs[funcName], funcOpt)
+		err := r.addOverload(funcName, &overload{id: overloadID, opt: funcOpt})
+		if err != nil {
+			return err
+		}
 	}
 
 	if _, ok := v.(traits.Container); ok && typ.HasTrait(traits.ContainerType) {
@@ -324,57 +546,113 @@ func (r *Registry) registerTraits(v ref.Val) error {
 		funcName := operators.In
 		overloadID := getOverloadID(funcName, argsCelType, resultType, false)
 		funcOpt := cel.Overload(overloadID, argsCelType, resultType, cel.OverloadOperandTrait(traits.ContainerType))
-		r.funcs[funcName] = append(r.funcs[funcName], funcOpt)
+		err := r.addOverload(funcName, &overload{id: overloadID, opt: funcOpt})
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func (r *Registry) registerFunction(name string, fun interface{}, member bool) error {
+func (r *Registry) registerFunction(name string, fun interface{}, member, replace bool, fnOpts []FunctionOption) error {
+	var config functionConfig
+	for _, opt := range fnOpts {
+		opt(&config)
+	}
+
 	funVal := reflect.ValueOf(fun)
 	if funVal.Kind() != reflect.Func {
 		return fmt.Errorf("func must be func")
 	}
-	typ := funVal.Type()
-	overloadOpt, err := r.getOverloadOpt(typ, funVal)
+	o, err := r.newFuncOverload(name, funVal, member, config)
 	if err != nil {
 		return err
 	}
+	if replace {
+		if owner, ok := r.overloads[o.id]; ok && owner.name != name {
+			return fmt.Errorf("function %s: overload ID %s already used by %s", name, o.id, owner)
+		}
+		r.replaceOverload(name, o)
+		return nil
+	}
+	return r.addOverload(name, o)
+}
+
+// newFuncOverload declares the Go func as an overload of the function.
+func (r *Registry) newFuncOverload(name string, funVal reflect.Value, member bool, config functionConfig) (*overload, error) {
+	typ := funVal.Type()
+	err := checkFuncResults(typ)
+	if err != nil {
+		return nil, err
+	}
 
 	numIn := typ.NumIn()
 	if member {
 		if numIn == 0 {
-			return fmt.Errorf("method must have at least one argument")
+			return nil, fmt.Errorf("method must have at least one argument")
+		}
+	}
+	if config.paramNames != nil {
+		numParams := numIn
+		if member {
+			numParams--
+		}
+		if len(config.paramNames) != numParams {
+			return nil, fmt.Errorf("%d param names given for %d params of %s", len(config.paramNames), numParams, typ.String())
 		}
 	}
 	argsCelType := make([]*cel.Type, 0, numIn)
 	argsReflectType := make([]reflect.Type, 0, numIn)
+	interfaceArgs := false
 	for i := 0; i < numIn; i++ {
 		in := typ.In(i)
-		celType, ok := convertToCelType(in)
+		celType, ok := r.nativeTypeProvider.convertToCelType(in)
 		if !ok {
-			return fmt.Errorf("invalid input type %s", in.String())
+			return nil, fmt.Errorf("invalid input type %s", in.String())
+		}
+		if in.Kind() == reflect.Interface && celType.Kind() == types.StructKind {
+			interfaceArgs = true
 		}
 		argsCelType = append(argsCelType, celType)
 		argsReflectType = append(argsReflectType, in)
 	}
 
 	out := typ.Out(0)
-	resultType, ok := convertToCelType(out)
+	resultType, ok := r.nativeTypeProvider.convertToCelType(out)
 	if !ok {
-		return fmt.Errorf("invalid output type %s", out.String())
+		return nil, fmt.Errorf("invalid output type %s", out.String())
 	}
 
-	overloadID := getOverloadID(name, argsCelType, resultType, member)
+	overloadID := config.overloadID
+	if overloadID == "" {
+		overloadID = getOverloadID(name, argsCelType, resultType, member)
+	}
+	overloadOpt, err := r.getOverloadOpt(typ, funVal, name, overloadID)
+	if err != nil {
+		return nil, err
+	}
 	opts := []cel.OverloadOpt{overloadOpt}
+	if len(config.examples) != 0 {
+		opts = append(opts, cel.OverloadExamples(config.examples...))
+	}
 	var funcOpt cel.FunctionOpt
 	if member {
 		funcOpt = cel.MemberOverload(overloadID, argsCelType, resultType, opts...)
 	} else {
 		funcOpt = cel.Overload(overloadID, argsCelType, resultType, opts...)
 	}
-	r.funcs[name] = append(r.funcs[name], funcOpt)
-	return nil
+	return &overload{
+		id:       overloadID,
+		opt:      funcOpt,
+		fun:      funVal,
+		member:   member,
+		argTypes: getTypesID(argsCelType),
+		config:   config,
+		// Values of registered interfaces carry the concrete type at runtime,
+		// which the runtime type guards would reject.
+		disableTypeGuards: interfaceArgs,
+	}, nil
 }
 
 func getOverloadID(name string, args []*cel.Type, resultType *cel.Type, member bool) string {
@@ -395,19 +673,30 @@ func getTypesID(types []*cel.Type) string {
 	return out
 }
 
-func (r *Registry) getOverloadOpt(typ reflect.Type, funVal reflect.Value) (out cel.OverloadOpt, err error) {
+func checkFuncResults(typ reflect.Type) error {
 	numOut := typ.NumOut()
 	switch numOut {
 	default:
-		return nil, fmt.Errorf("too many result")
+		return fmt.Errorf("too many result")
 	case 0:
-		return nil, fmt.Errorf("result is required")
+		return fmt.Errorf("result is required")
 	case 2:
 		if !typ.Out(1).AssignableTo(errorType) {
-			return nil, fmt.Errorf("last result must be error %s", typ.String())
+			return fmt.Errorf("last result must be error %s", typ.String())
 		}
 	case 1:
 	}
+	return nil
+}
+
+func (r *Registry) getOverloadOpt(typ reflect.Type, funVal reflect.Value, name, overloadID string) (out cel.OverloadOpt, err error) {
+	wrapErr := func(err error) ref.Val {
+		return types.WrapErr(&FunctionError{
+			Function: name,
+			Overload: overloadID,
+			Err:      err,
+		})
+	}
 
 	numIn := typ.NumIn()
 	rawTypes := make([]reflect.Type, numIn)
@@ -421,9 +710,9 @@ func (r *Registry) getOverloadOpt(typ reflect.Type, funVal reflect.Value) (out c
 	switch numIn {
 	case 1:
 		return cel.UnaryBinding(func(value ref.Val) ref.Val {
-			v, err := value.ConvertToNative(rawTypes[0])
+			v, err := convertToNative(value, rawTypes[0])
 			if err != nil {
-				return types.WrapErr(err)
+				return wrapErr(err)
 			}
 			val, err := reflectFuncCall(funVal,
 				[]reflect.Value{
@@ -431,19 +720,19 @@ func (r *Registry) getOverloadOpt(typ reflect.Type, funVal reflect.Value) (out c
 				},
 			)
 			if err != nil {
-				return types.WrapErr(err)
+				return wrapErr(err)
 			}
 			return r.NativeToValue(val.Interface())
 		}), nil
 	case 2:
 		return cel.BinaryBinding(func(lhs ref.Val, rhs ref.Val) ref.Val {
-			lhv, err := lhs.ConvertToNative(rawTypes[0])
+			lhv, err := convertToNative(lhs, rawTypes[0])
 			if err != nil {
-				return types.WrapErr(err)
+				return wrapErr(err)
 			}
-			rhv, err := rhs.ConvertToNative(rawTypes[1])
+			rhv, err := convertToNative(rhs, rawTypes[1])
 			if err != nil {
-				return types.WrapErr(err)
+				return wrapErr(err)
 			}
 			val, err := reflectFuncCall(funVal,
 				[]reflect.Value{
@@ -452,7 +741,7 @@ func (r *Registry) getOverloadOpt(typ reflect.Type, funVal reflect.Value) (out c
 				},
 			)
 			if err != nil {
-				return types.WrapErr(err)
+				return wrapErr(err)
 			}
 			return r.NativeToValue(val.Interface())
 		}), nil
@@ -460,7 +749,7 @@ func (r *Registry) getOverloadOpt(typ reflect.Type, funVal reflect.Value) (out c
 		return cel.FunctionBinding(func(values ...ref.Val) ref.Val {
 			val, err := reflectFuncCall(funVal, []reflect.Value{})
 			if err != nil {
-				return types.WrapErr(err)
+				return wrapErr(err)
 			}
 			return r.NativeToValue(val.Interface())
 		}), nil
@@ -468,9 +757,9 @@ func (r *Registry) getOverloadOpt(typ reflect.Type, funVal reflect.Value) (out c
 		return cel.FunctionBinding(func(values ...ref.Val) ref.Val {
 			vals := make([]reflect.Value, 0, len(values))
 			for i, value := range values {
-				val, err := value.ConvertToNative(rawTypes[i])
+				val, err := convertToNative(value, rawTypes[i])
 				if err != nil {
-					return types.WrapErr(err)
+					return wrapErr(err)
 				}
 				vals = append(vals,
 					reflect.ValueOf(val),
@@ -478,7 +767,7 @@ func (r *Registry) getOverloadOpt(typ reflect.Type, funVal reflect.Value) (out c
 			}
 			val, err := reflectFuncCall(funVal, vals)
 			if err != nil {
-				return types.WrapErr(err)
+				return wrapErr(err)
 			}
 			return r.NativeToValue(val.Interface())
 		}), nil
@@ -512,3 +801,18 @@ func fieldNameWithTag(field reflect.StructField, tagName string) (name string, e
 	}
 	return name, true
 }
+
+func fieldTagHasOption(field reflect.StructField, tagName string, option string) bool {
+	value, ok := field.Tag.Lookup(tagName)
+	if !ok {
+		return false
+	}
+
+	opts := strings.Split(value, ",")
+	for _, opt := range opts[1:] {
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,239 @@
+package easycel
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/google/cel-go/cel"
+)
+
+// ReloadableEnvironment is an environment built from a registry which can be rebuilt from an updated registry
+// and swapped in atomically, e.g. when the functions and types of plugins change at runtime.
+// Programs are cached by source for the current registry, programs already returned keep evaluating
+// against the registry they were compiled with.
+type ReloadableEnvironment struct {
+	opts    []cel.EnvOption
+	current atomic.Pointer[envSnapshot]
+
+	// reloadMu serializes reloads.
+	reloadMu sync.Mutex
+	// mu guards sources and swapping in a snapshot, so that a source is either checked by a reload
+	// or recorded after the swap.
+	mu sync.RWMutex
+	// sources are the expressions compiled by Program, checked against the registry of a reload.
+	sources map[string]struct{}
+}
+
+// envSnapshot is an environment with the programs compiled by it.
+type envSnapshot struct {
+	env      *Environment
+	registry *Registry
+	programs sync.Map // source -> *snapshotProgram
+}
+
+// snapshotProgram is a program created on first use.
+type snapshotProgram struct {
+	once    sync.Once
+	ast     *cel.Ast
+	program cel.Program
+	err     error
+}
+
+// NewReloadableEnvironment creates a reloadable environment from a snapshot of the registry,
+// registrations on the registry after the call are only used once it is passed to Reload.
+func NewReloadableEnvironment(registry *Registry, opts ...cel.EnvOption) (*ReloadableEnvironment, error) {
+	e := &ReloadableEnvironment{
+		opts:    opts,
+		sources: map[string]struct{}{},
+	}
+	snapshot, err := e.newSnapshot(registry)
+	if err != nil {
+		return nil, err
+	}
+	e.current.Store(snapshot)
+	return e, nil
+}
+
+func (e *ReloadableEnvironment) newSnapshot(registry *Registry) (*envSnapshot, error) {
+	registry, err := registry.Clone()
+	if err != nil {
+		return nil, err
+	}
+	env, err := NewEnvironment(append([]cel.EnvOption{cel.Lib(registry)}, e.opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	return &envSnapshot{
+		env:      env,
+		registry: registry,
+	}, nil
+}
+
+// Environment returns the environment of the current registry.
+func (e *ReloadableEnvironment) Environment() *Environment {
+	return e.current.Load().env
+}
+
+// Registry returns the snapshot of the current registry, which must not be modified.
+func (e *ReloadableEnvironment) Registry() *Registry {
+	return e.current.Load().registry
+}
+
+// Program returns the program of the source compiled with the current registry,
+// the source is compiled once per registry and checked again by Reload.
+// It doesn't wait for a running Reload, a source compiled while the registry is swapped in is compiled again with it.
+func (e *ReloadableEnvironment) Program(src string) (cel.Program, error) {
+	for {
+		snapshot := e.current.Load()
+		program, err := snapshot.program(src)
+		if err != nil {
+			return nil, err
+		}
+		if e.record(src, snapshot) {
+			return program, nil
+		}
+	}
+}
+
+// record records the source compiled with the snapshot,
+// reporting false if it is recorded too late to be checked by the reload which swapped in another snapshot.
+func (e *ReloadableEnvironment) record(src string, snapshot *envSnapshot) bool {
+	e.mu.RLock()
+	_, ok := e.sources[src]
+	e.mu.RUnlock()
+	if ok {
+		return true
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.sources[src] = struct{}{}
+	return e.current.Load() == snapshot
+}
+
+// Forget stops checking the source on Reload and drops its cached program.
+func (e *ReloadableEnvironment) Forget(src string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.sources, src)
+	e.current.Load().programs.Delete(src)
+}
+
+// Reload rebuilds the environment from a snapshot of the registry and swaps it in,
+// after checking that all the sources compiled by Program still compile.
+// If any doesn't, the current registry is kept and the failures are returned as *ReloadError.
+// Programs are created again on first use with the new registry,
+// evaluations and calls to Program are not blocked while the sources are checked.
+func (e *ReloadableEnvironment) Reload(registry *Registry) error {
+	e.reloadMu.Lock()
+	defer e.reloadMu.Unlock()
+
+	snapshot, err := e.newSnapshot(registry)
+	if err != nil {
+		return err
+	}
+	failures := map[string]*ExpressionError{}
+	checked := map[string]struct{}{}
+	for {
+		// Sources recorded while checking are checked as well before the swap.
+		e.mu.Lock()
+		var pending []string
+		for _, src := range sortedKeys(e.sources) {
+			if _, ok := checked[src]; !ok {
+				pending = append(pending, src)
+			}
+		}
+		if len(pending) == 0 {
+			defer e.mu.Unlock()
+			break
+		}
+		e.mu.Unlock()
+
+		for _, src := range pending {
+			checked[src] = struct{}{}
+			ast, err := snapshot.env.Compile(src)
+			if err != nil {
+				failures[src] = &ExpressionError{
+					Source: src,
+					Err:    err,
+				}
+				continue
+			}
+			snapshot.programs.Store(src, &snapshotProgram{ast: ast})
+		}
+	}
+
+	var errs []*ExpressionError
+	for _, src := range sortedKeys(failures) {
+		// Sources forgotten while checking are not reported.
+		if _, ok := e.sources[src]; ok {
+			errs = append(errs, failures[src])
+		}
+	}
+	if len(errs) != 0 {
+		return &ReloadError{Failures: errs}
+	}
+	e.current.Store(snapshot)
+	return nil
+}
+
+func (s *envSnapshot) program(src string) (cel.Program, error) {
+	v, _ := s.programs.LoadOrStore(src, &snapshotProgram{})
+	p := v.(*snapshotProgram)
+	p.once.Do(func() {
+		if p.ast == nil {
+			p.ast, p.err = s.env.Compile(src)
+			if p.err != nil {
+				return
+			}
+		}
+		p.program, p.err = s.env.ProgramFromAst(p.ast)
+	})
+	if p.err != nil {
+		// Failures are not cached, the entry is removed for the next call to try again.
+		s.programs.CompareAndDelete(src, p)
+		return nil, p.err
+	}
+	return p.program, nil
+}
+
+// ExpressionError is the compile error of a source.
+type ExpressionError struct {
+	Source string
+	Err    error
+}
+
+// Error implements the error interface method.
+func (e *ExpressionError) Error() string {
+	return fmt.Sprintf("%q: %v", e.Source, e.Err)
+}
+
+// Unwrap returns the compile error.
+func (e *ExpressionError) Unwrap() error {
+	return e.Err
+}
+
+// ReloadError reports the sources failing to compile with the registry passed to Reload,
+// which was not swapped in.
+type ReloadError struct {
+	Failures []*ExpressionError
+}
+
+// Error implements the error interface method.
+func (e *ReloadError) Error() string {
+	msgs := make([]string, 0, len(e.Failures))
+	for _, failure := range e.Failures {
+		msgs = append(msgs, failure.Error())
+	}
+	return fmt.Sprintf("reload: %d expressions fail to compile: %s", len(e.Failures), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the failures.
+func (e *ReloadError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failures))
+	for _, failure := range e.Failures {
+		errs = append(errs, failure)
+	}
+	return errs
+}
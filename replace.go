@@ -0,0 +1,174 @@
+package easycel
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+// ReplaceFunction registers the function like RegisterFunction,
+// replacing the overload with the same signature if one is registered.
+func (r *Registry) ReplaceFunction(name string, fun interface{}, opts ...FunctionOption) error {
+	return r.registerFunction(name, fun, false, true, opts)
+}
+
+// ReplaceMethod registers the method like RegisterMethod,
+// replacing the overload with the same signature if one is registered.
+func (r *Registry) ReplaceMethod(name string, fun interface{}, opts ...FunctionOption) error {
+	return r.registerFunction(name, fun, true, true, opts)
+}
+
+// UnregisterFunction removes the function or method with all its overloads.
+func (r *Registry) UnregisterFunction(name string) error {
+	fn, ok := r.funcs[name]
+	if !ok {
+		return fmt.Errorf("function %s not registered", name)
+	}
+	for _, o := range fn.overloads {
+		delete(r.overloads, o.id)
+	}
+	delete(r.funcs, name)
+	return nil
+}
+
+// UnregisterOverload removes the overload with the ID, as listed by Functions,
+// and the function if it has no overloads left.
+func (r *Registry) UnregisterOverload(id string) error {
+	for name, fn := range r.funcs {
+		for _, o := range fn.overloads {
+			if o.id == id {
+				r.removeOverload(name, id)
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("overload %s not registered", id)
+}
+
+// ReplaceVariable registers the variable like RegisterVariable,
+// replacing the variable with the same name if one is registered.
+// The registered variable is kept if the value can't be declared.
+func (r *Registry) ReplaceVariable(name string, val interface{}) error {
+	typ, err := variableValueType(name, val)
+	if err != nil {
+		return err
+	}
+	celType, err := r.variableCelType(name, typ)
+	if err != nil {
+		return err
+	}
+	if _, ok := r.variables[name]; ok {
+		err := r.UnregisterVariable(name)
+		if err != nil {
+			return err
+		}
+	}
+	err = r.DeclareVariableType(name, celType)
+	if err != nil {
+		return err
+	}
+	r.variableTypes[name] = typ
+	return nil
+}
+
+// UnregisterVariable removes the variable,
+// the fields of the input registered with RegisterInput can't be removed.
+func (r *Registry) UnregisterVariable(name string) error {
+	if _, ok := r.variables[name]; !ok {
+		return fmt.Errorf("variable %s not registered", name)
+	}
+	if _, ok := r.inputFields[name]; ok && r.inputType != nil {
+		return fmt.Errorf("variable %s is a field of input %v", name, r.inputType)
+	}
+	delete(r.variables, name)
+	delete(r.variableTypes, name)
+	delete(r.variableDocs, name)
+	delete(r.lazyVariables, name)
+	delete(r.inheritedVariables, name)
+	return nil
+}
+
+// UnregisterType removes the native type registered with RegisterType, RegisterTypeAs or RegisterInterface.
+// Types still used by a registered type, variable or function can't be removed,
+// nor can CEL values as the CEL type registry doesn't support removal.
+func (r *Registry) UnregisterType(refTypes any) error {
+	switch refTypes.(type) {
+	case ref.Val, ref.Type:
+		return fmt.Errorf("CEL type %T can't be unregistered", refTypes)
+	}
+	rawType := reflect.TypeOf(refTypes)
+	if rawType != nil && rawType.Kind() == reflect.Pointer && rawType.Elem().Kind() == reflect.Interface {
+		rawType = rawType.Elem()
+	}
+	tp := r.nativeTypeProvider
+	name, ok := tp.typeNames[rawType]
+	if !ok {
+		return fmt.Errorf("type %v not registered", rawType)
+	}
+	err := r.checkTypeUnused(name)
+	if err != nil {
+		return err
+	}
+	delete(tp.nativeTypes, name)
+	delete(tp.typeNames, rawType)
+	clear(tp.typeValues)
+	return nil
+}
+
+// checkTypeUnused returns an error naming a declaration using the CEL type.
+func (r *Registry) checkTypeUnused(name string) error {
+	for _, t := range r.Types() {
+		if t.Name == name {
+			continue
+		}
+		for _, impl := range t.Implementations {
+			if impl == name {
+				return fmt.Errorf("type %s is implementing interface %s", name, t.Name)
+			}
+		}
+		for _, field := range t.Fields {
+			if usesType(field.Type, name) {
+				return fmt.Errorf("type %s is used by field %s.%s", name, t.Name, field.Name)
+			}
+		}
+	}
+	return r.checkTypeUndeclared(name)
+}
+
+// checkTypeUndeclared returns an error naming a variable or function overload declared with the CEL type.
+func (r *Registry) checkTypeUndeclared(name string) error {
+	for _, v := range r.Variables() {
+		if usesType(v.Type, name) {
+			return fmt.Errorf("type %s is used by variable %s", name, v.Name)
+		}
+	}
+	functions, err := r.Functions()
+	if err != nil {
+		return err
+	}
+	for _, fn := range functions {
+		for _, o := range fn.Overloads {
+			for _, arg := range append(o.ArgTypes, o.ResultType) {
+				if usesType(arg, name) {
+					return fmt.Errorf("type %s is used by overload %s", name, o.ID)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// usesType reports whether the CEL type is or is parameterized by the named type.
+func usesType(t *cel.Type, name string) bool {
+	if t.TypeName() == name {
+		return true
+	}
+	for _, param := range t.Parameters() {
+		if usesType(param, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package easycel
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common"
+	"github.com/google/cel-go/common/types"
+)
+
+// ExpectType makes Compile fail unless the expression evaluates to the CEL type of the Go type,
+// expressions of type dyn are accepted and checked when converting the result.
+func ExpectType(typ reflect.Type) CompileOption {
+	return func(c *compileConfig) {
+		c.expectType = typ
+	}
+}
+
+// ExpectTypeOf is ExpectType with the Go type T.
+func ExpectTypeOf[T any]() CompileOption {
+	return ExpectType(reflect.TypeOf((*T)(nil)).Elem())
+}
+
+// checkOutputType returns *Diagnostics pointing at the root expression if its type isn't the expected one.
+func (e *Environment) checkOutputType(source common.Source, checked *cel.Ast, typ reflect.Type) error {
+	tp := defaultTypeProvider
+	if r := e.registry(); r != nil {
+		tp = r.nativeTypeProvider
+	}
+	want, ok := tp.convertToCelType(typ)
+	if !ok {
+		return fmt.Errorf("expected type %s not supported", typ.String())
+	}
+	got := checked.OutputType()
+	if got.Kind() == types.DynKind || want.Kind() == types.DynKind || want.IsAssignableType(got) {
+		return nil
+	}
+	message := fmt.Sprintf("expression of type %s, expected %s", got, want)
+	return newExprDiagnostics(source, checked, checked.NativeRep().Expr().ID(), message)
+}
+
+var defaultTypeProvider = newNativeTypeProvider("easycel", nil, nil)
+
+// EvalAs evaluates the program and converts the result to the Go type T with Decode,
+// for programs created by an Environment with the Decode of its registry,
+// so that the tag name and reverse conversions of the registry are used.
+func EvalAs[T any](prg cel.Program, vars any) (T, error) {
+	var out T
+	if vars == nil {
+		vars = map[string]any{}
+	}
+	val, _, err := prg.Eval(vars)
+	if err != nil {
+		return out, err
+	}
+	if p, ok := prg.(*program); ok && p.registry != nil {
+		err = p.registry.Decode(val, &out)
+	} else {
+		err = Decode(val, &out)
+	}
+	if err != nil {
+		return out, fmt.Errorf("convert result: %w", err)
+	}
+	return out, nil
+}
+
+// EvalBool evaluates the program to a bool, e.g. a filter.
+func EvalBool(program cel.Program, vars any) (bool, error) {
+	return EvalAs[bool](program, vars)
+}
+
+// EvalString evaluates the program to a string, e.g. a template.
+func EvalString(program cel.Program, vars any) (string, error) {
+	return EvalAs[string](program, vars)
+}
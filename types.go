@@ -35,17 +35,15 @@ var (
 	refValType            = reflect.TypeOf((*ref.Val)(nil)).Elem()
 )
 
-var typeValueMap = map[reflect.Type]*types.Type{}
-
-func getTypeValue(rawType reflect.Type) (typ *types.Type) {
-	typ, ok := typeValueMap[rawType]
+func (tp *nativeTypeProvider) getTypeValue(rawType reflect.Type) (typ *types.Type) {
+	typ, ok := tp.typeValues[rawType]
 	if ok {
 		return typ
 	}
 
 	switch rawType.Kind() {
 	case reflect.Struct:
-		typ = cel.ObjectType(rawTypeName(rawType), getTrait(rawType))
+		typ = cel.ObjectType(tp.typeName(rawType), getTrait(rawType))
 	case reflect.Bool:
 		typ = cel.BoolType
 	case reflect.Float32, reflect.Float64:
@@ -67,17 +65,28 @@ func getTypeValue(rawType reflect.Type) (typ *types.Type) {
 		if rawElem == byteType {
 			typ = cel.BytesType
 		} else {
-			typ = cel.ListType(getTypeValue(rawElem))
+			typ = cel.ListType(tp.getTypeValue(rawElem))
 		}
 	case reflect.Array:
-		typ = cel.ListType(getTypeValue(rawType.Elem()))
+		rawElem := rawType.Elem()
+		if rawElem == byteType {
+			typ = cel.BytesType
+		} else {
+			typ = cel.ListType(tp.getTypeValue(rawElem))
+		}
 	case reflect.Map:
-		typ = cel.MapType(getTypeValue(rawType.Key()), getTypeValue(rawType.Elem()))
+		typ = cel.MapType(tp.getTypeValue(rawType.Key()), tp.getTypeValue(rawType.Elem()))
 	case reflect.Ptr:
-		typ = cel.NullableType(getTypeValue(rawType.Elem()))
+		typ = cel.NullableType(tp.getTypeValue(rawType.Elem()))
+	case reflect.Interface:
+		if name, ok := tp.typeNames[rawType]; ok {
+			typ = cel.ObjectType(name)
+		} else {
+			typ = cel.DynType
+		}
 	}
 
-	typeValueMap[rawType] = typ
+	tp.typeValues[rawType] = typ
 	return typ
 }
 
@@ -1,16 +1,21 @@
 package easycel
 
 import (
+	"fmt"
 	"reflect"
+	"sort"
 
 	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
 )
 
 // convertToCelType converts the Golang reflect.Type to CEL type
-func convertToCelType(refType reflect.Type) (*cel.Type, bool) {
+func (tp *nativeTypeProvider) convertToCelType(refType reflect.Type) (*cel.Type, bool) {
 	switch refType.Kind() {
 	case reflect.Pointer:
-		ptrType, ok := convertToCelType(refType.Elem())
+		ptrType, ok := tp.convertToCelType(refType.Elem())
 		if !ok {
 			return nil, false
 		}
@@ -35,23 +40,27 @@ func convertToCelType(refType reflect.Type) (*cel.Type, bool) {
 		if refElem == byteType {
 			return cel.BytesType, true
 		}
-		elemType, ok := convertToCelType(refElem)
+		elemType, ok := tp.convertToCelType(refElem)
 		if !ok {
 			return nil, false
 		}
 		return cel.ListType(elemType), true
 	case reflect.Array:
-		elemType, ok := convertToCelType(refType.Elem())
+		refElem := refType.Elem()
+		if refElem == byteType {
+			return cel.BytesType, true
+		}
+		elemType, ok := tp.convertToCelType(refElem)
 		if !ok {
 			return nil, false
 		}
 		return cel.ListType(elemType), true
 	case reflect.Map:
-		keyType, ok := convertToCelType(refType.Key())
+		keyType, ok := tp.convertToCelType(refType.Key())
 		if !ok {
 			return nil, false
 		}
-		elemType, ok := convertToCelType(refType.Elem())
+		elemType, ok := tp.convertToCelType(refType.Elem())
 		if !ok {
 			return nil, false
 		}
@@ -60,9 +69,55 @@ func convertToCelType(refType reflect.Type) (*cel.Type, bool) {
 		if refType == timestampType || refType == typesTimestampType {
 			return cel.TimestampType, true
 		}
-		return cel.ObjectType(rawTypeName(refType)), true
+		return cel.ObjectType(tp.typeName(refType)), true
 	case reflect.Interface:
+		if name, ok := tp.typeNames[refType]; ok {
+			return cel.ObjectType(name), true
+		}
 		return cel.DynType, true
 	}
 	return nil, false
 }
+
+// convertToNative converts the CEL value to the Golang reflect.Type,
+// lists are converted to fixed-size arrays only when their length matches.
+func convertToNative(val ref.Val, refType reflect.Type) (any, error) {
+	if refType.Kind() != reflect.Array {
+		return val.ConvertToNative(refType)
+	}
+	if reflect.TypeOf(val.Value()) == refType {
+		return val.Value(), nil
+	}
+	list, ok := val.(traits.Lister)
+	if !ok {
+		return val.ConvertToNative(refType)
+	}
+
+	size, ok := list.Size().(types.Int)
+	if !ok {
+		return nil, fmt.Errorf("type conversion error from '%v' to '%v'", val.Type(), refType)
+	}
+	if int(size) != refType.Len() {
+		return nil, fmt.Errorf("list of length %d not assignable to %v", size, refType)
+	}
+
+	out := reflect.New(refType).Elem()
+	for i := 0; i < int(size); i++ {
+		elem, err := convertToNative(list.Get(types.Int(i)), refType.Elem())
+		if err != nil {
+			return nil, err
+		}
+		out.Index(i).Set(reflect.ValueOf(elem))
+	}
+	return out.Interface(), nil
+}
+
+// sortedKeys returns the keys of the map in sorted order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}